@@ -0,0 +1,149 @@
+package rck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AutoKind is the shape of an Auto result.
+type AutoKind string
+
+// Kinds of Auto results
+const (
+	AutoText       AutoKind = "text"       // A JSON string, as from the pure engine
+	AutoStructured AutoKind = "structured" // Any other JSON value: objects, arrays, numbers, booleans
+	AutoImage      AutoKind = "image"      // A list of image data URLs
+)
+
+// ErrAutoKind is returned by AutoResult accessors that do not match the result's kind.
+var ErrAutoKind = errors.New("rck: auto result has a different kind")
+
+// AutoResult is the result of Kernel.Auto. Use Kind to find out which accessor applies:
+//
+//	result, err := client.Compute.Auto(ctx, params)
+//	switch result.Kind() {
+//	case rck.AutoText:
+//		text, _ := result.Text()
+//	case rck.AutoImage:
+//		images, _ := result.Image()
+//	case rck.AutoStructured:
+//		err = result.Decode(&value)
+//	}
+type AutoResult struct {
+	kind     AutoKind
+	engine   Engine
+	response UnifiedAPIResponse
+}
+
+// newAutoResult classifies a response. The output must not be empty. routed is the engine
+// sent with the request, or "" when the server chose.
+func newAutoResult(response UnifiedAPIResponse, routed Engine) *AutoResult {
+	result := &AutoResult{kind: AutoStructured, engine: response.Engine, response: response}
+	if result.engine == "" {
+		result.engine = routed
+	}
+	var text string
+	var urls []string
+	switch {
+	case json.Unmarshal(response.Output, &text) == nil:
+		result.kind = AutoText
+	case result.engine == EngineImage:
+		result.kind = AutoImage
+	case json.Unmarshal(response.Output, &urls) == nil && len(urls) > 0 && allDataURLs(urls):
+		result.kind = AutoImage
+	}
+	return result
+}
+
+func allDataURLs(urls []string) bool {
+	for _, url := range urls {
+		if !strings.HasPrefix(url, "data:") {
+			return false
+		}
+	}
+	return true
+}
+
+// Kind returns the shape of the output.
+func (r *AutoResult) Kind() AutoKind {
+	return r.kind
+}
+
+// Engine returns the engine that ran the request: the one the server reported, or the one
+// sent with AutoParams.ClientRouting. It is "" when neither is known.
+func (r *AutoResult) Engine() Engine {
+	return r.engine
+}
+
+// Response returns the raw API response.
+func (r *AutoResult) Response() *UnifiedAPIResponse {
+	return &r.response
+}
+
+// Raw returns the raw JSON output.
+func (r *AutoResult) Raw() json.RawMessage {
+	return r.response.Output
+}
+
+// Decode unmarshals the output into v, whatever its kind.
+func (r *AutoResult) Decode(v interface{}) error {
+	return json.Unmarshal(r.response.Output, v)
+}
+
+// Text returns the output of a text result.
+func (r *AutoResult) Text() (string, error) {
+	if err := r.expect(AutoText); err != nil {
+		return "", err
+	}
+	return decodeText(r.response.Output), nil
+}
+
+// Structured returns a structured result as a ComputeResponse.
+func (r *AutoResult) Structured() (*ComputeResponse, error) {
+	if err := r.expect(AutoStructured); err != nil {
+		return nil, err
+	}
+	return NewComputeResponse(r.response), nil
+}
+
+// Image returns the images of an image result.
+func (r *AutoResult) Image() (*ImageResponse, error) {
+	if err := r.expect(AutoImage); err != nil {
+		return nil, err
+	}
+	return decodeImageResponse(&r.response)
+}
+
+func (r *AutoResult) expect(kind AutoKind) error {
+	if r.kind != kind {
+		return fmt.Errorf("%w: %s, not %s", ErrAutoKind, r.kind, kind)
+	}
+	return nil
+}
+
+// AutoPlan is the engine Kernel.PlanAuto chose and why.
+type AutoPlan struct {
+	Engine Engine
+	Reason string
+}
+
+func (p AutoPlan) String() string {
+	return fmt.Sprintf("%s: %s", p.Engine, p.Reason)
+}
+
+// AutoAs calls Kernel.Auto and decodes the output into a value of type T, such as a
+// struct or map for structured output, a string for text or []string for image data URLs.
+func AutoAs[T any](ctx context.Context, kernel *Kernel, params AutoParams) (T, error) {
+	var value T
+	result, err := kernel.Auto(ctx, params)
+	if err != nil {
+		return value, err
+	}
+	if err := result.Decode(&value); err != nil {
+		return value, fmt.Errorf("failed to decode %s output into %T: %w", result.Kind(), value, err)
+	}
+	return value, nil
+}
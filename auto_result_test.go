@@ -0,0 +1,177 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+// autoWith runs Auto against a server whose auto handler replies with reply.
+func autoWith(t *testing.T, reply rcktest.Reply) (*rck.AutoResult, error) {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle(rck.EngineAuto, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		return reply
+	})
+	options := server.ClientOptions()
+	options.Retry = &rck.RetryPolicy{MaxAttempts: 1}
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.Compute.Auto(context.Background(), rck.AutoParams{Input: "hello", FunctionLogic: "do it"})
+}
+
+func TestAutoResultKinds(t *testing.T) {
+	image := rcktest.FakeImageDataURL()
+	tests := []struct {
+		name   string
+		reply  rcktest.Reply
+		kind   rck.AutoKind
+		engine rck.Engine
+		raw    string
+	}{
+		{"string", rcktest.Reply{Output: "hi", Engine: rck.EnginePure}, rck.AutoText, rck.EnginePure, `"hi"`},
+		{"object", rcktest.Reply{Output: map[string]interface{}{"a": 1}, Engine: rck.EngineStandard}, rck.AutoStructured, rck.EngineStandard, `{"a":1}`},
+		{"array", rcktest.Reply{Output: []int{1, 2}}, rck.AutoStructured, "", `[1,2]`},
+		{"number", rcktest.Reply{Output: 4.5}, rck.AutoStructured, "", `4.5`},
+		{"boolean", rcktest.Reply{Output: true}, rck.AutoStructured, "", `true`},
+		{"data URLs", rcktest.Reply{Output: []string{image, image}}, rck.AutoImage, "", `["` + image + `","` + image + `"]`},
+		{"image engine", rcktest.Reply{Output: []string{image}, Engine: rck.EngineImage}, rck.AutoImage, rck.EngineImage, `["` + image + `"]`},
+		{"strings that are not images", rcktest.Reply{Output: []string{"a", image}}, rck.AutoStructured, "", `["a","` + image + `"]`},
+		{"empty list", rcktest.Reply{Output: []string{}}, rck.AutoStructured, "", `[]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := autoWith(t, tt.reply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Kind() != tt.kind || result.Engine() != tt.engine {
+				t.Errorf("kind, engine = %s, %q, want %s, %q", result.Kind(), result.Engine(), tt.kind, tt.engine)
+			}
+			if string(result.Raw()) != tt.raw {
+				t.Errorf("Raw = %s, want %s", result.Raw(), tt.raw)
+			}
+
+			// Exactly the accessor of the result's kind succeeds.
+			_, textErr := result.Text()
+			_, structuredErr := result.Structured()
+			_, imageErr := result.Image()
+			for kind, err := range map[rck.AutoKind]error{rck.AutoText: textErr, rck.AutoStructured: structuredErr, rck.AutoImage: imageErr} {
+				if kind == tt.kind && err != nil {
+					t.Errorf("%s accessor: %v", kind, err)
+				}
+				if kind != tt.kind && !errors.Is(err, rck.ErrAutoKind) {
+					t.Errorf("%s accessor error = %v, want ErrAutoKind", kind, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAutoResultAccessors(t *testing.T) {
+	result, err := autoWith(t, rcktest.Reply{Output: "hello there"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := result.Text(); text != "hello there" {
+		t.Errorf("Text = %q", text)
+	}
+
+	result, err = autoWith(t, rcktest.Reply{Output: map[string]interface{}{"name": "Ada"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value struct{ Name string }
+	if err := result.Decode(&value); err != nil || value.Name != "Ada" {
+		t.Errorf("Decode = %+v, %v", value, err)
+	}
+	structured, err := result.Structured()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields, err := structured.AsMap(); err != nil || fields["name"] != "Ada" {
+		t.Errorf("Structured = %v, %v", fields, err)
+	}
+
+	result, err = autoWith(t, rcktest.Reply{Output: []string{rcktest.FakeImageDataURL()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	images, err := result.Image()
+	if err != nil || images.Count != 1 || images.Images[0].MimeType != "image/png" {
+		t.Errorf("Image = %+v, %v", images, err)
+	}
+}
+
+func TestAutoRejectsEmptyOutput(t *testing.T) {
+	_, err := autoWith(t, rcktest.Reply{})
+	var apiErr *rck.APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != rck.KindInvalidResponse {
+		t.Errorf("error = %v, want an invalid response", err)
+	}
+}
+
+func TestPlanAutoMatchesServerRouting(t *testing.T) {
+	style, err := rck.ParsePromptTemplate("style", "", "watercolor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"a": map[string]interface{}{"type": "string"}}}
+	examples := []rck.Example{{Input: "x", Output: map[string]interface{}{"a": "y"}}}
+	tests := []struct {
+		name   string
+		params rck.AutoParams
+		want   rck.Engine
+	}{
+		{"logic only", rck.AutoParams{FunctionLogic: "do it"}, rck.EnginePure},
+		{"schema", rck.AutoParams{FunctionLogic: "do it", OutputDataClass: schema}, rck.EngineStandard},
+		{"empty schema string", rck.AutoParams{FunctionLogic: "do it", OutputDataClass: ""}, rck.EnginePure},
+		{"image field", rck.AutoParams{Lighting: "dusk"}, rck.EngineImage},
+		{"image field over schema", rck.AutoParams{FunctionLogic: "do it", Style: "ink", OutputDataClass: schema}, rck.EngineImage},
+		{"templated image field", rck.AutoParams{FunctionLogic: "do it", Templates: map[string]*rck.Prompt{"Style": style.With(nil)}}, rck.EngineImage},
+		{"examples", rck.AutoParams{Examples: examples}, rck.EngineAttractor},
+		{"examples over everything", rck.AutoParams{Examples: examples, FrameComposition: "wide", OutputDataClass: schema}, rck.EngineAttractor},
+	}
+
+	server := rcktest.NewServer()
+	defer server.Close()
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.params.Input = "hello"
+			plan := client.Compute.PlanAuto(tt.params)
+			if plan.Engine != tt.want || plan.Reason == "" {
+				t.Errorf("PlanAuto = %v, want %s", plan, tt.want)
+			}
+
+			// The fake server's routing, which stands in for the real server's, agrees.
+			result, err := client.Compute.Auto(context.Background(), tt.params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Engine() != tt.want {
+				t.Errorf("server routed to %s, want %s", result.Engine(), tt.want)
+			}
+
+			// With ClientRouting the planned engine is sent.
+			tt.params.ClientRouting = true
+			if _, err := client.Compute.Auto(context.Background(), tt.params); err != nil {
+				t.Fatal(err)
+			}
+			requests := server.Requests()
+			last := requests[len(requests)-1]
+			if last.Config == nil || last.Config.Engine != tt.want {
+				t.Errorf("ClientRouting sent config %+v, want engine %s", last.Config, tt.want)
+			}
+		})
+	}
+}
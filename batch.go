@@ -0,0 +1,204 @@
+package rck
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultBatchConcurrency = 4
+
+// BatchOptions controls how a batch of requests is executed.
+type BatchOptions struct {
+	Concurrency int                   // Maximum number of requests in flight; defaults to 4
+	FailFast    bool                  // Stop scheduling new items after the first failure
+	OnProgress  func(p BatchProgress) // Called after each item completes; must be safe for concurrent use
+}
+
+// BatchProgress reports the state of a running batch.
+type BatchProgress struct {
+	Completed int // Items finished, successfully or not
+	Failed    int // Items finished with an error
+	Total     int // Number of items, or -1 when reading from a channel
+}
+
+// BatchResult is the outcome of a single item in a batch.
+type BatchResult[T any] struct {
+	Index int // Position of the item in the input
+	Value T
+	Err   error
+}
+
+// RunBatch calls fn for every item with bounded concurrency and returns the results in input order.
+//
+// The returned error is nil when every item was attempted. It is the context's
+// error when ctx was canceled before every item started, or the first item error
+// when FailFast is set; in both cases the results gathered so far are still
+// returned, and items that never ran carry that error. With FailFast, items that
+// were in flight and failed because of the cancellation also carry the first error.
+func RunBatch[P, R any](ctx context.Context, items []P, opts BatchOptions, fn func(context.Context, P) (R, error)) ([]BatchResult[R], error) {
+	feedCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	in := make(chan P)
+	go func() {
+		defer close(in)
+		for _, item := range items {
+			select {
+			case in <- item:
+			case <-feedCtx.Done():
+				return
+			}
+		}
+	}()
+	return runBatch(ctx, in, len(items), opts, fn)
+}
+
+// RunBatchChan is like RunBatch but reads items from a channel until it is closed.
+func RunBatchChan[P, R any](ctx context.Context, items <-chan P, opts BatchOptions, fn func(context.Context, P) (R, error)) ([]BatchResult[R], error) {
+	return runBatch(ctx, items, -1, opts, fn)
+}
+
+func runBatch[P, R any](ctx context.Context, items <-chan P, total int, opts BatchOptions, fn func(context.Context, P) (R, error)) ([]BatchResult[R], error) {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		results  []BatchResult[R]
+		progress = BatchProgress{Total: total}
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	if total > 0 {
+		results = make([]BatchResult[R], 0, total)
+	}
+	sem := make(chan struct{}, concurrency)
+
+	index := 0
+	drained := false
+schedule:
+	for {
+		var item P
+		var ok bool
+		select {
+		case item, ok = <-items:
+			if !ok {
+				drained = true
+				break schedule
+			}
+		case <-runCtx.Done():
+			// Tell a drained channel apart from one that still had items.
+			select {
+			case _, ok := <-items:
+				if !ok {
+					drained = true
+				} else {
+					mu.Lock()
+					results = append(results, BatchResult[R]{Index: index})
+					mu.Unlock()
+				}
+			default:
+			}
+			break schedule
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-runCtx.Done():
+		}
+		if runCtx.Err() != nil {
+			// Both cases may be ready; never start an item after cancellation.
+			mu.Lock()
+			results = append(results, BatchResult[R]{Index: index})
+			mu.Unlock()
+			break schedule
+		}
+
+		mu.Lock()
+		results = append(results, BatchResult[R]{Index: index})
+		mu.Unlock()
+
+		wg.Add(1)
+		go func(i int, item P) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			value, err := fn(runCtx, item)
+
+			mu.Lock()
+			results[i].Value = value
+			results[i].Err = err
+			progress.Completed++
+			if err != nil {
+				progress.Failed++
+				switch {
+				case opts.FailFast && firstErr == nil:
+					firstErr = err
+					cancel()
+				case firstErr != nil && ctx.Err() == nil && errors.Is(err, context.Canceled):
+					// Canceled by FailFast, not by the caller.
+					results[i].Err = firstErr
+				}
+			}
+			snapshot := progress
+			mu.Unlock()
+
+			if opts.OnProgress != nil {
+				opts.OnProgress(snapshot)
+			}
+		}(index, item)
+		index++
+	}
+	wg.Wait()
+
+	// Record items that were never started so callers can tell them apart from successes.
+	for len(results) < total {
+		results = append(results, BatchResult[R]{Index: len(results)})
+	}
+
+	// Cancellation only matters if it kept items from starting.
+	stoppedEarly := !drained
+	if total >= 0 {
+		stoppedEarly = index < total
+	}
+	stopErr := firstErr
+	if stopErr == nil && stoppedEarly {
+		stopErr = ctx.Err()
+	}
+	if stopErr != nil {
+		for i := index; i < len(results); i++ {
+			results[i].Err = stopErr
+		}
+	}
+	return results, stopErr
+}
+
+// BatchStructuredTransform runs StructuredTransform for every item with bounded concurrency.
+// Results are returned in input order; see RunBatch for error semantics.
+func (k *Kernel) BatchStructuredTransform(ctx context.Context, params []StructuredTransformParams, opts BatchOptions, config ...ComputeConfig) ([]BatchResult[*ComputeResponse], error) {
+	return RunBatch(ctx, params, opts, func(ctx context.Context, p StructuredTransformParams) (*ComputeResponse, error) {
+		return k.StructuredTransform(ctx, p, config...)
+	})
+}
+
+// BatchLearnFromExamples runs LearnFromExamples for every item with bounded concurrency.
+// Results are returned in input order; see RunBatch for error semantics.
+func (k *Kernel) BatchLearnFromExamples(ctx context.Context, params []LearnFromExamplesParams, opts BatchOptions, config ...ComputeConfig) ([]BatchResult[*ComputeResponse], error) {
+	return RunBatch(ctx, params, opts, func(ctx context.Context, p LearnFromExamplesParams) (*ComputeResponse, error) {
+		return k.LearnFromExamples(ctx, p, config...)
+	})
+}
+
+// BatchGenerateText runs GenerateText for every item with bounded concurrency.
+// Results are returned in input order; see RunBatch for error semantics.
+func (k *Kernel) BatchGenerateText(ctx context.Context, params []GenerateTextParams, opts BatchOptions, config ...ComputeConfig) ([]BatchResult[string], error) {
+	return RunBatch(ctx, params, opts, func(ctx context.Context, p GenerateTextParams) (string, error) {
+		return k.GenerateText(ctx, p, config...)
+	})
+}
@@ -0,0 +1,138 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+func TestRunBatchReturnsResultsInOrder(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	results, err := rck.RunBatch(context.Background(), items, rck.BatchOptions{Concurrency: 3}, func(ctx context.Context, n int) (int, error) {
+		return n * n, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Value != items[i]*items[i] || r.Err != nil {
+			t.Errorf("results[%d] = %+v", i, r)
+		}
+	}
+}
+
+func TestRunBatchCancelAfterLastItem(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := []int{1, 2, 3}
+	completed := 0
+	results, err := rck.RunBatch(ctx, items, rck.BatchOptions{
+		Concurrency: 1,
+		OnProgress: func(p rck.BatchProgress) {
+			completed = p.Completed
+			if p.Completed == p.Total {
+				cancel()
+			}
+		},
+	}, func(ctx context.Context, n int) (int, error) {
+		return n, nil
+	})
+	if err != nil {
+		t.Errorf("error = %v, want nil once every item finished", err)
+	}
+	if completed != len(items) || len(results) != len(items) {
+		t.Errorf("completed %d of %d results, want %d", completed, len(results), len(items))
+	}
+}
+
+func TestRunBatchCancelStopsScheduling(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	items := []int{0, 1, 2, 3, 4}
+	results, err := rck.RunBatch(ctx, items, rck.BatchOptions{Concurrency: 1}, func(ctx context.Context, n int) (int, error) {
+		if n == 1 {
+			cancel()
+		}
+		return n, nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("error = %v, want context.Canceled", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("got %d results, want %d", len(results), len(items))
+	}
+	for _, r := range results[:2] {
+		if r.Err != nil {
+			t.Errorf("finished item %d error = %v", r.Index, r.Err)
+		}
+	}
+	for _, r := range results[2:] {
+		if !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("unstarted item %d error = %v, want context.Canceled", r.Index, r.Err)
+		}
+	}
+}
+
+func TestRunBatchFailFast(t *testing.T) {
+	boom := errors.New("boom")
+	started := make(chan struct{})
+	items := []int{0, 1, 2, 3, 4, 5}
+	results, err := rck.RunBatch(context.Background(), items, rck.BatchOptions{Concurrency: 2, FailFast: true}, func(ctx context.Context, n int) (int, error) {
+		switch n {
+		case 0:
+			// Stays in flight until the failure cancels it.
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		case 1:
+			<-started
+			return 0, boom
+		}
+		return n, nil
+	})
+	if err != boom {
+		t.Fatalf("error = %v, want %v", err, boom)
+	}
+	for _, r := range results {
+		if r.Err != boom {
+			t.Errorf("item %d error = %v, want %v", r.Index, r.Err, boom)
+		}
+	}
+}
+
+func TestRunBatchChan(t *testing.T) {
+	items := make(chan string, 3)
+	items <- "a"
+	items <- "b"
+	items <- "c"
+	close(items)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var last rck.BatchProgress
+	results, err := rck.RunBatchChan(ctx, items, rck.BatchOptions{
+		Concurrency: 1,
+		OnProgress: func(p rck.BatchProgress) {
+			last = p
+			if p.Completed == 3 {
+				cancel()
+			}
+		},
+	}, func(ctx context.Context, s string) (string, error) {
+		return s + s, nil
+	})
+	if err != nil {
+		t.Fatalf("error = %v, want nil once the channel was drained", err)
+	}
+	if last.Total != -1 || last.Completed != 3 {
+		t.Errorf("progress = %+v, want 3 completed of unknown total", last)
+	}
+	if len(results) != 3 || results[2].Value != "cc" {
+		t.Errorf("results = %+v", results)
+	}
+}
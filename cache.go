@@ -0,0 +1,297 @@
+package rck
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache stores successful responses by request key. Implementations must be safe for concurrent use.
+// Caching is best effort: a backend that fails to read or write simply reports a miss.
+type Cache interface {
+	Get(key string) (*UnifiedAPIResponse, bool)
+	Set(key string, response *UnifiedAPIResponse)
+}
+
+// CacheOptions configures the response cache used by Kernel calls.
+type CacheOptions struct {
+	Backend Cache // Where responses are stored, e.g. NewMemoryCache or NewDiskCache
+
+	// CacheNonDeterministic also caches requests with a temperature above 0,
+	// whose output is expected to differ between calls. It is off by default.
+	CacheNonDeterministic bool
+}
+
+// CacheStats counts cache lookups of Kernel calls.
+type CacheStats struct {
+	Hits     int64 // Responses served from the cache
+	Misses   int64 // Cacheable requests sent to the server
+	Bypassed int64 // Requests not looked up: bypassed per call or non-deterministic
+}
+
+// HitRate returns the fraction of cacheable requests served from the cache.
+func (s CacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+type bypassCacheKey struct{}
+
+// WithoutCache returns a context whose Kernel calls skip the response cache, neither reading nor storing.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassCacheKey{}, true)
+}
+
+func cacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(bypassCacheKey{}).(bool)
+	return bypass
+}
+
+// CacheKey returns the canonical hash of a request used as its cache key. Requests that
+// differ only in JSON formatting of OutputDataClass or example outputs, in a missing
+// versus auto engine, or in streaming, share a key.
+func CacheKey(request *UnifiedAPIRequest) (string, error) {
+	normalized := UnifiedAPIRequest{Program: request.Program, Config: &APIConfig{}}
+	if request.Config != nil {
+		*normalized.Config = *request.Config
+	}
+	if normalized.Config.Engine == "" {
+		normalized.Config.Engine = EngineAuto
+	}
+	normalized.Config.Stream = false
+
+	pipeline := &normalized.Program.Pipeline
+	pipeline.OutputDataClass = canonicalJSON(pipeline.OutputDataClass)
+	if len(pipeline.Examples) > 0 {
+		pipeline.Examples = append([]APIExample(nil), pipeline.Examples...)
+		for i := range pipeline.Examples {
+			pipeline.Examples[i].Output = canonicalJSON(pipeline.Examples[i].Output)
+		}
+	}
+
+	data, err := json.Marshal(&normalized)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalJSON re-encodes a JSON document with sorted keys and no insignificant whitespace.
+// Text that is not JSON is returned unchanged.
+func canonicalJSON(text string) string {
+	var value interface{}
+	if text == "" || json.Unmarshal([]byte(text), &value) != nil {
+		return text
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return text
+	}
+	return string(data)
+}
+
+// responseCache applies CacheOptions in front of the HTTP client.
+type responseCache struct {
+	options CacheOptions
+
+	hits     atomic.Int64
+	misses   atomic.Int64
+	bypassed atomic.Int64
+}
+
+func newResponseCache(options *CacheOptions) *responseCache {
+	if options == nil || options.Backend == nil {
+		return nil
+	}
+	return &responseCache{options: *options}
+}
+
+// cacheable reports whether the request may be served from and stored in the cache.
+func (c *responseCache) cacheable(ctx context.Context, request *UnifiedAPIRequest) bool {
+	if cacheBypassed(ctx) {
+		return false
+	}
+	if request.Config == nil || c.options.CacheNonDeterministic {
+		return true
+	}
+	temperature := request.Config.Temperature
+	return temperature == nil || *temperature <= 0
+}
+
+// do serves the request from the cache or sends it with send, storing successful responses.
+// Responses rejected by valid are neither served nor stored; a nil valid accepts all.
+func (c *responseCache) do(ctx context.Context, request *UnifiedAPIRequest, send func() (*UnifiedAPIResponse, error), valid func(*UnifiedAPIResponse) bool) (*UnifiedAPIResponse, error) {
+	if c == nil {
+		return send()
+	}
+	if !c.cacheable(ctx, request) {
+		c.bypassed.Add(1)
+		return send()
+	}
+	key, err := CacheKey(request)
+	if err != nil {
+		c.bypassed.Add(1)
+		return send()
+	}
+	if response, ok := c.options.Backend.Get(key); ok && (valid == nil || valid(response)) {
+		c.hits.Add(1)
+		return response, nil
+	}
+
+	c.misses.Add(1)
+	response, err := send()
+	if err != nil {
+		return nil, err
+	}
+	if valid == nil || valid(response) {
+		c.options.Backend.Set(key, response)
+	}
+	return response, nil
+}
+
+func (c *responseCache) stats() CacheStats {
+	if c == nil {
+		return CacheStats{}
+	}
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Bypassed: c.bypassed.Load()}
+}
+
+func copyResponse(response *UnifiedAPIResponse) *UnifiedAPIResponse {
+	clone := *response
+	clone.Output = append(json.RawMessage(nil), response.Output...)
+	return &clone
+}
+
+// MemoryCache is an in-memory LRU cache with an optional time to live.
+type MemoryCache struct {
+	maxEntries int
+	ttl        time.Duration
+
+	mu      sync.Mutex
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+type memoryEntry struct {
+	key      string
+	response *UnifiedAPIResponse
+	storedAt time.Time
+}
+
+// NewMemoryCache creates an LRU cache holding at most maxEntries responses (0 means unbounded)
+// for at most ttl each (0 means no expiry).
+func NewMemoryCache(maxEntries int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{maxEntries: maxEntries, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get returns the response stored under key, if present and not expired.
+func (m *MemoryCache) Get(key string) (*UnifiedAPIResponse, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*memoryEntry)
+	if m.ttl > 0 && time.Since(entry.storedAt) > m.ttl {
+		m.order.Remove(element)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.order.MoveToFront(element)
+	return copyResponse(entry.response), true
+}
+
+// Set stores a response under key, evicting the least recently used entry when full.
+func (m *MemoryCache) Set(key string, response *UnifiedAPIResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry := &memoryEntry{key: key, response: copyResponse(response), storedAt: time.Now()}
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Len returns the number of stored entries, including expired ones not yet evicted.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// DiskCache stores responses as JSON files in a directory, one file per key.
+// It can be shared between processes.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+type diskEntry struct {
+	StoredAt time.Time           `json:"stored_at"`
+	Response *UnifiedAPIResponse `json:"response"`
+}
+
+// NewDiskCache creates a cache in dir, creating the directory if needed.
+// Entries older than ttl are ignored (0 means no expiry).
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir, ttl: ttl}, nil
+}
+
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.dir, key+".json")
+}
+
+// Get returns the response stored under key, if present and not expired.
+func (d *DiskCache) Get(key string) (*UnifiedAPIResponse, bool) {
+	data, err := os.ReadFile(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry diskEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Response == nil {
+		return nil, false
+	}
+	if d.ttl > 0 && time.Since(entry.StoredAt) > d.ttl {
+		os.Remove(d.path(key))
+		return nil, false
+	}
+	return entry.Response, true
+}
+
+// Set stores a response under key. The file is written atomically.
+func (d *DiskCache) Set(key string, response *UnifiedAPIResponse) {
+	data, err := json.Marshal(diskEntry{StoredAt: time.Now().UTC(), Response: response})
+	if err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(d.dir, key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, writeErr := tmp.Write(data)
+	closeErr := tmp.Close()
+	if writeErr != nil || closeErr != nil || os.Rename(tmp.Name(), d.path(key)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
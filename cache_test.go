@@ -0,0 +1,250 @@
+package rck_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func mustCacheKey(t *testing.T, request *rck.UnifiedAPIRequest) string {
+	t.Helper()
+	key, err := rck.CacheKey(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCacheKeyNormalization(t *testing.T) {
+	base := func() *rck.UnifiedAPIRequest {
+		return &rck.UnifiedAPIRequest{
+			Program: rck.APIProgram{
+				Input: rck.APIInput{Input: "hello"},
+				Pipeline: rck.APIPipeline{
+					FunctionLogic:   "extract",
+					OutputDataClass: `{"type": "object", "required": ["a"]}`,
+					Examples:        []rck.APIExample{{Input: "x", Output: `{"a": 1, "b": 2}`}},
+				},
+			},
+		}
+	}
+	key := mustCacheKey(t, base())
+
+	same := map[string]func(*rck.UnifiedAPIRequest){
+		"schema formatting": func(r *rck.UnifiedAPIRequest) {
+			r.Program.Pipeline.OutputDataClass = "{\n  \"required\": [\"a\"],\n  \"type\": \"object\"\n}"
+		},
+		"example formatting": func(r *rck.UnifiedAPIRequest) {
+			r.Program.Pipeline.Examples = []rck.APIExample{{Input: "x", Output: `{"b":2,"a":1}`}}
+		},
+		"auto engine": func(r *rck.UnifiedAPIRequest) { r.Config = &rck.APIConfig{Engine: rck.EngineAuto} },
+		"stream":      func(r *rck.UnifiedAPIRequest) { r.Config = &rck.APIConfig{Stream: true} },
+	}
+	for name, change := range same {
+		request := base()
+		change(request)
+		if got := mustCacheKey(t, request); got != key {
+			t.Errorf("%s: key changed", name)
+		}
+	}
+
+	different := map[string]func(*rck.UnifiedAPIRequest){
+		"input":   func(r *rck.UnifiedAPIRequest) { r.Program.Input.Input = "bye" },
+		"schema":  func(r *rck.UnifiedAPIRequest) { r.Program.Pipeline.OutputDataClass = `{"type": "array"}` },
+		"example": func(r *rck.UnifiedAPIRequest) { r.Program.Pipeline.Examples[0].Output = `{"a": 2}` },
+		"engine":  func(r *rck.UnifiedAPIRequest) { r.Config = &rck.APIConfig{Engine: rck.EngineStandard} },
+	}
+	for name, change := range different {
+		request := base()
+		change(request)
+		if got := mustCacheKey(t, request); got == key {
+			t.Errorf("%s: key unchanged", name)
+		}
+	}
+
+	// Normalizing must not modify the caller's request.
+	request := base()
+	mustCacheKey(t, request)
+	if request.Config != nil || request.Program.Pipeline.Examples[0].Output != `{"a": 1, "b": 2}` {
+		t.Errorf("request modified: %+v", request)
+	}
+}
+
+func cachedResponse(output string) *rck.UnifiedAPIResponse {
+	return &rck.UnifiedAPIResponse{Output: json.RawMessage(output), Engine: rck.EnginePure}
+}
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := rck.NewMemoryCache(2, 0)
+	cache.Set("a", cachedResponse(`"a"`))
+	cache.Set("b", cachedResponse(`"b"`))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a missing")
+	}
+	cache.Set("c", cachedResponse(`"c"`))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used entry b was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if got, ok := cache.Get(key); !ok || string(got.Output) != `"`+key+`"` {
+			t.Errorf("Get(%q) = %v, %v", key, got, ok)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len = %d, want 2", cache.Len())
+	}
+}
+
+func TestMemoryCacheExpiresEntries(t *testing.T) {
+	cache := rck.NewMemoryCache(0, 20*time.Millisecond)
+	cache.Set("a", cachedResponse(`"a"`))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("fresh entry missing")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("expired entry returned")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("Len = %d, want the expired entry evicted", cache.Len())
+	}
+}
+
+func TestMemoryCacheReturnsCopies(t *testing.T) {
+	cache := rck.NewMemoryCache(0, 0)
+	stored := cachedResponse(`"abc"`)
+	cache.Set("a", stored)
+	stored.Output[1] = 'x'
+	got, _ := cache.Get("a")
+	got.Output[2] = 'y'
+	if again, _ := cache.Get("a"); string(again.Output) != `"abc"` {
+		t.Errorf("cached output = %s, want it unaffected by callers", again.Output)
+	}
+}
+
+func TestDiskCacheRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	cache, err := rck.NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("key", &rck.UnifiedAPIResponse{Output: json.RawMessage(`{"a":1}`), Engine: rck.EngineStandard, Details: "d"})
+
+	// A second cache on the same directory, as in another process, sees the entry.
+	other, err := rck.NewDiskCache(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := other.Get("key")
+	if !ok || string(got.Output) != `{"a":1}` || got.Engine != rck.EngineStandard || got.Details != "d" {
+		t.Errorf("Get = %+v, %v", got, ok)
+	}
+	if _, ok := other.Get("missing"); ok {
+		t.Error("Get found a missing key")
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != "key.json" {
+		t.Errorf("cache directory holds %v, want only key.json", entries)
+	}
+}
+
+func TestDiskCacheExpiresEntries(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := rck.NewDiskCache(dir, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("key", cachedResponse(`"a"`))
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := cache.Get("key"); ok {
+		t.Error("expired entry returned")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "key.json")); !os.IsNotExist(err) {
+		t.Errorf("expired file kept: %v", err)
+	}
+}
+
+func newCachedClient(t *testing.T, options rck.CacheOptions) (*rck.Client, *rcktest.Server) {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	clientOptions := server.ClientOptions()
+	options.Backend = rck.NewMemoryCache(0, 0)
+	clientOptions.Cache = &options
+	client, err := rck.NewClient("test-key", clientOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestClientServesRepeatedCallsFromCache(t *testing.T) {
+	client, server := newCachedClient(t, rck.CacheOptions{})
+	for i := 0; i < 3; i++ {
+		if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.RequestCount(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+	if stats := client.CacheStats(); stats.Hits != 2 || stats.Misses != 1 || stats.HitRate() < 0.66 {
+		t.Errorf("stats = %+v, want 2 hits and 1 miss", stats)
+	}
+}
+
+func TestClientCacheSkipsNonDeterministicCalls(t *testing.T) {
+	temperature := 0.7
+	config := rck.ComputeConfig{Temperature: &temperature}
+
+	client, server := newCachedClient(t, rck.CacheOptions{})
+	for i := 0; i < 2; i++ {
+		if _, err := client.Compute.GenerateText(context.Background(), textParams, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.RequestCount(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+	if stats := client.CacheStats(); stats.Bypassed != 2 || stats.Hits != 0 {
+		t.Errorf("stats = %+v, want 2 bypassed", stats)
+	}
+
+	client, server = newCachedClient(t, rck.CacheOptions{CacheNonDeterministic: true})
+	for i := 0; i < 2; i++ {
+		if _, err := client.Compute.GenerateText(context.Background(), textParams, config); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.RequestCount(); got != 1 {
+		t.Errorf("with CacheNonDeterministic: server received %d requests, want 1", got)
+	}
+}
+
+func TestWithoutCache(t *testing.T) {
+	client, server := newCachedClient(t, rck.CacheOptions{})
+	ctx := rck.WithoutCache(context.Background())
+	for i := 0; i < 2; i++ {
+		if _, err := client.Compute.GenerateText(ctx, textParams); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Bypassed calls store nothing either.
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.RequestCount(); got != 3 {
+		t.Errorf("server received %d requests, want 3", got)
+	}
+	if stats := client.CacheStats(); stats.Bypassed != 2 || stats.Misses != 1 || stats.Hits != 0 {
+		t.Errorf("stats = %+v, want 2 bypassed and 1 miss", stats)
+	}
+}
@@ -14,6 +14,7 @@ const (
 type Client struct {
 	Compute *Kernel
 	Image   *Generator
+	Jobs    *Jobs
 	client  *HttpClient
 }
 
@@ -37,14 +38,50 @@ func NewClient(apiKey string, options *ClientOptions) (*Client, error) {
 	}
 
 	httpClient := NewHttpClient(apiKey, baseURL, timeout)
+	if options != nil {
+		if options.HTTPClient != nil {
+			// Copy so the SDK never mutates the caller's client.
+			custom := *options.HTTPClient
+			if custom.Timeout == 0 {
+				custom.Timeout = timeout
+			}
+			httpClient.SetHTTPClient(&custom)
+		}
+		if options.Transport != nil {
+			httpClient.httpClient.Transport = options.Transport
+		}
+		httpClient.SetRetryPolicy(options.Retry)
+		httpClient.SetRateLimit(options.RateLimit)
+		httpClient.Use(options.Middleware...)
+		httpClient.SetDeduplicate(options.Deduplicate)
+	}
+
+	kernel := NewKernel(httpClient)
+	if options != nil {
+		kernel.SetCache(options.Cache)
+		kernel.SetSchemaRegistry(options.Schemas)
+	}
 
 	return &Client{
-		Compute: NewKernel(httpClient),
+		Compute: kernel,
 		Image:   NewGenerator(httpClient),
+		Jobs:    NewJobs(httpClient),
 		client:  httpClient,
 	}, nil
 }
 
+// LimiterStats returns wait statistics of the client-side rate limiter.
+// It returns zero values when ClientOptions.RateLimit was not set.
+func (c *Client) LimiterStats() LimiterStats {
+	return c.client.LimiterStats()
+}
+
+// CacheStats returns hit and miss counts of the response cache.
+// It returns zero values when ClientOptions.Cache was not set.
+func (c *Client) CacheStats() CacheStats {
+	return c.Compute.CacheStats()
+}
+
 // TestConnection sends a simple request to the API to verify connectivity and authentication.
 func (c *Client) TestConnection(ctx context.Context) error {
 	params := StructuredTransformParams{
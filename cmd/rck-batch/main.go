@@ -0,0 +1,392 @@
+// Command rck-batch runs a JSONL file of RCK requests and writes one result per line.
+//
+// Each input line is either a raw UnifiedAPIRequest:
+//
+//	{"config": {"engine": "pure"}, "program": {"input": {"input": "..."}, "Pipeline": {"FunctionLogic": "..."}}}
+//
+// or an envelope naming an SDK method, with params using the Go field names of the
+// corresponding params struct:
+//
+//	{"id": "c-1", "method": "structured_transform", "params": {"Input": "...", "FunctionLogic": "...", "OutputDataClass": {...}}}
+//	{"id": "c-2", "request": {"program": {...}}}
+//
+// Supported methods are structured_transform, analyze, translate, learn_from_examples,
+// generate_text, generate_image and auto. Lines without an id are identified as
+// "line-N"; a line that is not valid JSON gets an error result under that ID. With
+// -resume, IDs already present in the output file are skipped, so an interrupted run
+// can be restarted with the same arguments.
+//
+// Usage:
+//
+//	RCK_API_KEY=... rck-batch -in requests.jsonl -out results.jsonl -concurrency 8 -resume
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+// envelope is a single input line.
+type envelope struct {
+	ID      string                 `json:"id,omitempty"`
+	Method  string                 `json:"method,omitempty"`
+	Params  json.RawMessage        `json:"params,omitempty"`
+	Config  *rck.ComputeConfig     `json:"config,omitempty"`
+	Request *rck.UnifiedAPIRequest `json:"request,omitempty"`
+
+	// Program is set when the line is a bare UnifiedAPIRequest.
+	Program *rck.APIProgram `json:"program,omitempty"`
+}
+
+// result is a single output line.
+type result struct {
+	ID        string          `json:"id"`
+	Status    string          `json:"status"` // "ok" or "error"
+	LatencyMs int64           `json:"latency_ms"`
+	Output    json.RawMessage `json:"output,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type job struct {
+	id   string
+	line []byte
+	err  error // Set when the line could not be parsed
+}
+
+func main() {
+	var (
+		inPath      = flag.String("in", "", "input JSONL file (required)")
+		outPath     = flag.String("out", "results.jsonl", "output JSONL file")
+		concurrency = flag.Int("concurrency", 4, "maximum number of requests in flight")
+		resume      = flag.Bool("resume", false, "append to the output file and skip IDs already present in it")
+		retryFailed = flag.Bool("retry-failed", false, "with -resume, re-run IDs whose previous result was an error")
+		baseURL     = flag.String("base-url", "", "override the API base URL")
+		timeout     = flag.Int("timeout", 0, "per-request timeout in milliseconds")
+		retries     = flag.Int("retries", 3, "attempts per request, including the first")
+		apiKey      = flag.String("api-key", os.Getenv("RCK_API_KEY"), "API key (defaults to $RCK_API_KEY)")
+	)
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("rck-batch: ")
+
+	if *inPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	retryPolicy := rck.DefaultRetryPolicy()
+	retryPolicy.MaxAttempts = *retries
+	client, err := rck.NewClient(*apiKey, &rck.ClientOptions{
+		BaseURL: *baseURL,
+		Timeout: *timeout,
+		Retry:   retryPolicy,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	done := map[string]bool{}
+	if *resume {
+		if done, err = completedIDs(*outPath, *retryFailed); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	in, err := os.Open(*inPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer in.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if *resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	out, err := os.OpenFile(*outPath, flags, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer out.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	stats, batchErr := process(ctx, client, in, out, done, *concurrency)
+	log.Printf("processed %d, failed %d, interrupted %d, skipped %d", stats.processed, stats.failed, stats.interrupted, stats.skipped)
+
+	if batchErr != nil {
+		log.Fatalf("stopped early: %v (re-run with -resume to continue)", batchErr)
+	}
+	if stats.failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// runStats counts the outcome of a run.
+type runStats struct {
+	processed   int // Results written
+	failed      int // Results written with status "error", or that could not be written
+	interrupted int // Requests canceled or never started because of an interrupt; -resume runs them again
+	skipped     int // IDs already done
+}
+
+// process runs the jobs read from in, skipping IDs in done, and writes one result line per
+// finished request to out. A request canceled because ctx ended is not written.
+func process(ctx context.Context, client *rck.Client, in io.Reader, out io.Writer, done map[string]bool, concurrency int) (runStats, error) {
+	jobs := make(chan job)
+	readErr := make(chan error, 1)
+	skipped := 0
+	go func() {
+		defer close(jobs)
+		readErr <- readJobs(ctx, in, done, jobs, &skipped)
+	}()
+
+	var (
+		writeMu                  sync.Mutex
+		writer                   = bufio.NewWriter(out)
+		started, written, failed atomic.Int64
+		interrupted              atomic.Int64 // Started but canceled
+	)
+	results, batchErr := rck.RunBatchChan(ctx, jobs, rck.BatchOptions{Concurrency: concurrency}, func(ctx context.Context, j job) (struct{}, error) {
+		started.Add(1)
+		res, err := run(ctx, client, j)
+		if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+			// Leave interrupted requests out of the output so -resume runs them again.
+			interrupted.Add(1)
+			return struct{}{}, err
+		}
+
+		line, err := json.Marshal(res)
+		if err != nil {
+			failed.Add(1)
+			return struct{}{}, err
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := writer.Write(append(line, '\n')); err != nil {
+			failed.Add(1)
+			return struct{}{}, err
+		}
+		// Flush per line so an interrupted run can be resumed from the output file.
+		if err := writer.Flush(); err != nil {
+			failed.Add(1)
+			return struct{}{}, err
+		}
+		written.Add(1)
+		if res.Status != "ok" {
+			failed.Add(1)
+			return struct{}{}, errors.New(res.Error)
+		}
+		return struct{}{}, nil
+	})
+
+	// Wait for the reader before using skipped.
+	err := <-readErr
+	stats := runStats{
+		processed: int(written.Load()),
+		failed:    int(failed.Load()),
+		// Jobs taken off the queue by an interrupt never start and have no result line.
+		interrupted: int(interrupted.Load()) + len(results) - int(started.Load()),
+		skipped:     skipped,
+	}
+	if err != nil {
+		return stats, err
+	}
+	if batchErr == nil && stats.interrupted > 0 {
+		// Every request started, but some were canceled before they finished.
+		batchErr = ctx.Err()
+	}
+	return stats, batchErr
+}
+
+// readJobs streams input lines to jobs, skipping IDs in done. A line that is not valid
+// JSON becomes a job with an error, identified by its line number.
+func readJobs(ctx context.Context, r io.Reader, done map[string]bool, jobs chan<- job, skipped *int) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var header struct {
+			ID string `json:"id"`
+		}
+		var parseErr error
+		if err := json.Unmarshal(line, &header); err != nil {
+			parseErr = fmt.Errorf("line %d: invalid JSON: %w", lineNo, err)
+		}
+		id := header.ID
+		if id == "" {
+			id = fmt.Sprintf("line-%d", lineNo)
+		}
+		if done[id] {
+			*skipped++
+			continue
+		}
+
+		select {
+		case jobs <- job{id: id, line: append([]byte(nil), line...), err: parseErr}:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// completedIDs returns the IDs present in an existing output file.
+func completedIDs(path string, retryFailed bool) (map[string]bool, error) {
+	done := map[string]bool{}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var res result
+		// A truncated last line from an interrupted run is simply re-run.
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil || res.ID == "" {
+			continue
+		}
+		if retryFailed && res.Status != "ok" {
+			delete(done, res.ID)
+			continue
+		}
+		done[res.ID] = true
+	}
+	return done, scanner.Err()
+}
+
+func run(ctx context.Context, client *rck.Client, j job) (result, error) {
+	if j.err != nil {
+		return result{ID: j.id, Status: "error", Error: j.err.Error()}, j.err
+	}
+	start := time.Now()
+	output, err := dispatch(ctx, client, j.line)
+	res := result{ID: j.id, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		res.Status = "error"
+		res.Error = err.Error()
+		return res, err
+	}
+	res.Status = "ok"
+	res.Output = output
+	return res, nil
+}
+
+func dispatch(ctx context.Context, client *rck.Client, line []byte) (json.RawMessage, error) {
+	var env envelope
+	if err := json.Unmarshal(line, &env); err != nil {
+		return nil, err
+	}
+
+	if env.Program != nil {
+		var request rck.UnifiedAPIRequest
+		if err := json.Unmarshal(line, &request); err != nil {
+			return nil, err
+		}
+		env.Request = &request
+	}
+	if env.Request != nil {
+		response, err := client.Compute.Execute(ctx, *env.Request)
+		if err != nil {
+			return nil, err
+		}
+		return response.Output, nil
+	}
+
+	var config []rck.ComputeConfig
+	if env.Config != nil {
+		config = append(config, *env.Config)
+	}
+
+	switch env.Method {
+	case "structured_transform":
+		var params rck.StructuredTransformParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		return computeOutput(client.Compute.StructuredTransform(ctx, params, config...))
+	case "analyze":
+		var params rck.AnalyzeParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		return computeOutput(client.Compute.Analyze(ctx, params, config...))
+	case "translate":
+		var params rck.TranslateParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		return computeOutput(client.Compute.Translate(ctx, params, config...))
+	case "learn_from_examples":
+		var params rck.LearnFromExamplesParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		return computeOutput(client.Compute.LearnFromExamples(ctx, params, config...))
+	case "generate_text":
+		var params rck.GenerateTextParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		text, err := client.Compute.GenerateText(ctx, params, config...)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(text)
+	case "generate_image":
+		var params rck.GenerateParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		images, err := client.Image.Generate(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return images.RawData.Output, nil
+	case "auto":
+		var params rck.AutoParams
+		if err := json.Unmarshal(env.Params, &params); err != nil {
+			return nil, err
+		}
+		result, err := client.Compute.Auto(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Raw(), nil
+	case "":
+		return nil, errors.New(`line has neither "method", "request" nor "program"`)
+	default:
+		return nil, fmt.Errorf("unknown method %q", env.Method)
+	}
+}
+
+func computeOutput(response *rck.ComputeResponse, err error) (json.RawMessage, error) {
+	if err != nil {
+		return nil, err
+	}
+	return response.Raw(), nil
+}
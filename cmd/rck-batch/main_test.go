@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+// lockedBuffer is an io.Writer whose contents can be read while process writes to it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) results(t *testing.T) []result {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var results []result
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var res result
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("invalid output line %q: %v", line, err)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+const batchInput = `{"id":"fast-1","method":"generate_text","params":{"Input":"fast","FunctionLogic":"echo"}}
+{"id":"slow-1","method":"generate_text","params":{"Input":"slow","FunctionLogic":"echo"}}
+{"id":"fast-2","method":"generate_text","params":{"Input":"fast","FunctionLogic":"echo"}}
+`
+
+// newBatchServer returns a server whose "slow" requests block until release is closed.
+func newBatchServer(t *testing.T, release <-chan struct{}) *rck.Client {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle(rck.EnginePure, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		if req.Program.Input.Input == "slow" {
+			<-release
+		}
+		return rcktest.Reply{Output: "ok: " + req.Program.Input.Input}
+	})
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestInterruptedRequestsAreResumed(t *testing.T) {
+	release := make(chan struct{})
+	client := newBatchServer(t, release)
+	// Registered after the server's cleanup, so it runs first and unblocks pending handlers.
+	t.Cleanup(func() { close(release) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := &lockedBuffer{}
+	go func() {
+		for len(out.results(t)) < 2 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+	}()
+
+	stats, err := process(ctx, client, strings.NewReader(batchInput), out, map[string]bool{}, 3)
+	if err == nil {
+		t.Fatal("process returned nil error after an interrupt")
+	}
+	if stats.processed != 2 || stats.failed != 0 || stats.interrupted != 1 {
+		t.Errorf("stats = %+v, want 2 processed, 0 failed, 1 interrupted", stats)
+	}
+	for _, res := range out.results(t) {
+		if res.ID == "slow-1" {
+			t.Errorf("interrupted request was written: %+v", res)
+		}
+	}
+
+	// A plain -resume re-runs the interrupted request only.
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	if err := os.WriteFile(path, out.buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	done, err := completedIDs(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumeClient := newBatchServer(t, closedChannel())
+	resumed := &lockedBuffer{}
+	stats, err = process(context.Background(), resumeClient, strings.NewReader(batchInput), resumed, done, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.processed != 1 || stats.skipped != 2 {
+		t.Errorf("resume stats = %+v, want 1 processed, 2 skipped", stats)
+	}
+	if results := resumed.results(t); len(results) != 1 || results[0].ID != "slow-1" || results[0].Status != "ok" {
+		t.Errorf("resumed results = %+v, want slow-1 ok", results)
+	}
+}
+
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+func TestCompletedIDs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	content := `{"id":"a","status":"ok","latency_ms":1}
+{"id":"b","status":"error","latency_ms":1,"error":"boom"}
+{"id":"c","sta`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		retryFailed bool
+		want        map[string]bool
+	}{
+		{false, map[string]bool{"a": true, "b": true}},
+		{true, map[string]bool{"a": true}},
+	}
+	for _, tt := range tests {
+		done, err := completedIDs(path, tt.retryFailed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(done) != len(tt.want) {
+			t.Errorf("retryFailed=%v: done = %v, want %v", tt.retryFailed, done, tt.want)
+			continue
+		}
+		for id := range tt.want {
+			if !done[id] {
+				t.Errorf("retryFailed=%v: done = %v, want %v", tt.retryFailed, done, tt.want)
+			}
+		}
+	}
+
+	done, err := completedIDs(filepath.Join(t.TempDir(), "missing.jsonl"), false)
+	if err != nil || len(done) != 0 {
+		t.Errorf("missing file: done = %v, err = %v", done, err)
+	}
+}
+
+func TestFailedRequestsAreWritten(t *testing.T) {
+	client := newBatchServer(t, closedChannel())
+	input := `{"id":"bad","method":"nope"}` + "\n"
+	out := &lockedBuffer{}
+	stats, err := process(context.Background(), client, strings.NewReader(input), out, map[string]bool{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.processed != 1 || stats.failed != 1 {
+		t.Errorf("stats = %+v, want 1 processed, 1 failed", stats)
+	}
+	if results := out.results(t); len(results) != 1 || results[0].Status != "error" {
+		t.Errorf("results = %+v, want one error", results)
+	}
+}
+
+func TestUnstartedRequestsCountAsInterrupted(t *testing.T) {
+	release := make(chan struct{})
+	arrived := make(chan struct{})
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+	server.Handle(rck.EnginePure, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		if req.Program.Input.Input == "slow" {
+			close(arrived)
+			<-release
+		}
+		return rcktest.Reply{Output: "ok"}
+	})
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		// With one slot, fast-2 has been taken off the queue and waits for slow-1.
+		<-arrived
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	out := &lockedBuffer{}
+	stats, err := process(ctx, client, strings.NewReader(batchInput), out, map[string]bool{}, 1)
+	if err == nil {
+		t.Fatal("process returned nil error after an interrupt")
+	}
+	if stats.processed != 1 || stats.failed != 0 || stats.interrupted != 2 {
+		t.Errorf("stats = %+v, want 1 processed, 0 failed, 2 interrupted", stats)
+	}
+	if results := out.results(t); len(results) != 1 || results[0].ID != "fast-1" {
+		t.Errorf("results = %+v, want only fast-1", results)
+	}
+}
+
+func TestInvalidLinesAreWritten(t *testing.T) {
+	client := newBatchServer(t, closedChannel())
+	input := `{"id":"a","method":"generate_text","params":{"Input":"fast","FunctionLogic":"echo"}}
+{"id":"b", oops
+{"id":"c","method":"generate_text","params":{"Input":"fast","FunctionLogic":"echo"}}
+`
+	out := &lockedBuffer{}
+	stats, err := process(context.Background(), client, strings.NewReader(input), out, map[string]bool{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.processed != 3 || stats.failed != 1 {
+		t.Errorf("stats = %+v, want 3 processed, 1 failed", stats)
+	}
+	byID := map[string]result{}
+	for _, res := range out.results(t) {
+		byID[res.ID] = res
+	}
+	if byID["a"].Status != "ok" || byID["c"].Status != "ok" {
+		t.Errorf("results = %+v, want a and c ok", byID)
+	}
+	if bad := byID["line-2"]; bad.Status != "error" || !strings.Contains(bad.Error, "invalid JSON") {
+		t.Errorf("line-2 = %+v, want an invalid JSON error", bad)
+	}
+
+	// A resumed run skips the invalid line like any other finished ID.
+	done := map[string]bool{"a": true, "line-2": true, "c": true}
+	stats, err = process(context.Background(), client, strings.NewReader(input), &lockedBuffer{}, done, 2)
+	if err != nil || stats.skipped != 3 || stats.processed != 0 {
+		t.Errorf("resume stats = %+v, err = %v, want 3 skipped", stats, err)
+	}
+}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const sdkImport = "github.com/Askr-Omorsablin/rck-go-sdk"
+
+// generator accumulates the declarations of a generated file.
+type generator struct {
+	pkg      string
+	wrappers bool
+	imports  map[string]bool
+	names    map[string]bool // Declared identifiers
+	decls    []string
+}
+
+func newGenerator(pkg string, wrappers bool) *generator {
+	return &generator{pkg: pkg, wrappers: wrappers, imports: map[string]bool{}, names: map[string]bool{}}
+}
+
+// add generates the type, schema constant and wrapper for a schema with inlined references.
+func (g *generator) add(ref, typeName string, schema map[string]interface{}) error {
+	name, _, _ := strings.Cut(ref, "@")
+	if typeName == "" {
+		typeName = exportedName(name)
+	}
+	if !token.IsIdentifier(typeName) || !token.IsExported(typeName) {
+		return fmt.Errorf("%q is not an exported Go identifier", typeName)
+	}
+	if g.names[typeName] {
+		return fmt.Errorf("type %s is generated twice", typeName)
+	}
+
+	doc := fmt.Sprintf("%s is generated from the %s schema.", typeName, ref)
+	g.declare(typeName, doc, mergeAllOf(schema))
+
+	data, err := json.MarshalIndent(schema, "", "\t")
+	if err != nil {
+		return err
+	}
+	schemaConst := g.reserve(typeName + "Schema")
+	g.decls = append(g.decls, fmt.Sprintf("// %s is the %s schema, for use as OutputDataClass.\nconst %s = %s\n",
+		schemaConst, ref, schemaConst, goString(string(data))))
+
+	if g.wrappers {
+		g.imports["context"] = true
+		g.imports["fmt"] = true
+		g.imports[sdkImport] = true
+		wrapper := g.reserve("Transform" + typeName)
+		g.decls = append(g.decls, fmt.Sprintf(`// %[1]s calls StructuredTransform with %[2]s as OutputDataClass,
+// overriding params.OutputDataClass, and decodes the output as %[3]s.
+func %[1]s(ctx context.Context, kernel *rck.Kernel, params rck.StructuredTransformParams, config ...rck.ComputeConfig) (%[3]s, error) {
+	var result %[3]s
+	params.OutputDataClass = %[2]s
+	response, err := kernel.StructuredTransform(ctx, params, config...)
+	if err != nil {
+		return result, err
+	}
+	if err := response.Decode(&result); err != nil {
+		return result, fmt.Errorf("failed to decode output into %%T: %%w", result, err)
+	}
+	return result, nil
+}
+`, wrapper, schemaConst, typeName))
+	}
+	return nil
+}
+
+// source returns the formatted file.
+func (g *generator) source() ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rck-gen. DO NOT EDIT.\n\npackage %s\n\n", g.pkg)
+	if len(g.imports) > 0 {
+		imports := make([]string, 0, len(g.imports))
+		for path := range g.imports {
+			imports = append(imports, path)
+		}
+		sort.Strings(imports)
+		buf.WriteString("import (\n")
+		for _, path := range imports {
+			if path != sdkImport {
+				fmt.Fprintf(&buf, "\t%q\n", path)
+			}
+		}
+		if g.imports[sdkImport] {
+			fmt.Fprintf(&buf, "\n\trck %q\n", sdkImport)
+		}
+		buf.WriteString(")\n\n")
+	}
+	for _, decl := range g.decls {
+		buf.WriteString(decl)
+		buf.WriteString("\n")
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return buf.Bytes(), fmt.Errorf("generated invalid Go code: %w", err)
+	}
+	return source, nil
+}
+
+// reserve returns name, or name with a numeric suffix if it is already declared.
+func (g *generator) reserve(name string) string {
+	unique := name
+	for i := 2; g.names[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	g.names[unique] = true
+	return unique
+}
+
+// declare declares a named type for schema and returns its name.
+func (g *generator) declare(name, doc string, schema map[string]interface{}) string {
+	name = g.reserve(name)
+	// Reserve the position first so a type is declared before the types of its fields.
+	index := len(g.decls)
+	g.decls = append(g.decls, "")
+
+	var buf strings.Builder
+	writeComment(&buf, "", doc)
+	if description, ok := schema["description"].(string); ok && description != "" {
+		buf.WriteString("//\n")
+		writeComment(&buf, "", description)
+	}
+
+	if values, ok := stringEnum(schema); ok {
+		fmt.Fprintf(&buf, "type %s string\n\n", name)
+		fmt.Fprintf(&buf, "// Values of %s.\nconst (\n", name)
+		for i, value := range values {
+			constName := name + identifierPart(value)
+			if constName == name {
+				constName = name + "Value" + strconv.Itoa(i+1)
+			}
+			fmt.Fprintf(&buf, "\t%s %s = %s\n", g.reserve(constName), name, strconv.Quote(value))
+		}
+		buf.WriteString(")\n")
+		g.decls[index] = buf.String()
+		return name
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	required := map[string]bool{}
+	for _, field := range sliceOf(schema["required"]) {
+		if s, ok := field.(string); ok {
+			required[s] = true
+		}
+	}
+	props := make([]string, 0, len(properties))
+	for prop := range properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	fmt.Fprintf(&buf, "type %s struct {\n", name)
+	fields := map[string]bool{}
+	for _, prop := range props {
+		fieldName := exportedName(prop)
+		for i := 2; fields[fieldName]; i++ {
+			fieldName = exportedName(prop) + strconv.Itoa(i)
+		}
+		fields[fieldName] = true
+
+		propSchema, _ := properties[prop].(map[string]interface{})
+		fieldType, nullable := g.goType(name+fieldName, fmt.Sprintf("%s is the type of %s.%s.", name+fieldName, name, fieldName), propSchema)
+		tag := prop
+		if !required[prop] {
+			tag += ",omitempty"
+		}
+		if (!required[prop] || nullable) && pointerable(fieldType) {
+			fieldType = "*" + fieldType
+		}
+		if description, ok := propSchema["description"].(string); ok && description != "" {
+			writeComment(&buf, "\t", description)
+		}
+		fmt.Fprintf(&buf, "\t%s %s `json:%s`\n", fieldName, fieldType, strconv.Quote(tag))
+	}
+	buf.WriteString("}\n")
+	g.decls[index] = buf.String()
+	return name
+}
+
+// goType returns the Go type for schema, declaring a type called name for objects and enums.
+// It also reports whether the schema allows null.
+func (g *generator) goType(name, doc string, schema map[string]interface{}) (string, bool) {
+	schema = mergeAllOf(schema)
+	types, nullable := schemaTypes(schema)
+	if _, ok := stringEnum(schema); ok {
+		return g.declare(name, doc, schema), nullable
+	}
+	if len(types) == 0 && schema["properties"] != nil {
+		types = []string{"object"}
+	}
+	if len(types) != 1 {
+		return "interface{}", nullable
+	}
+
+	switch types[0] {
+	case "object":
+		if _, ok := schema["properties"].(map[string]interface{}); ok {
+			return g.declare(name, doc, schema), nullable
+		}
+		if values, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+			valueType, _ := g.goType(name+"Value", fmt.Sprintf("%sValue is the value type of %s.", name, name), values)
+			return "map[string]" + valueType, nullable
+		}
+		return "map[string]interface{}", nullable
+	case "array":
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return "[]interface{}", nullable
+		}
+		itemType, _ := g.goType(name+"Item", fmt.Sprintf("%sItem is the item type of %s.", name, name), items)
+		return "[]" + itemType, nullable
+	case "string":
+		if schema["format"] == "date-time" {
+			g.imports["time"] = true
+			return "time.Time", nullable
+		}
+		return "string", nullable
+	case "integer":
+		return "int", nullable
+	case "number":
+		return "float64", nullable
+	case "boolean":
+		return "bool", nullable
+	default:
+		return "interface{}", nullable
+	}
+}
+
+// schemaTypes returns the non-null types of a schema and whether null is allowed.
+func schemaTypes(schema map[string]interface{}) ([]string, bool) {
+	var types []string
+	nullable := false
+	switch t := schema["type"].(type) {
+	case string:
+		types = []string{t}
+	case []interface{}:
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+	}
+	kept := types[:0]
+	for _, t := range types {
+		if t == "null" {
+			nullable = true
+			continue
+		}
+		kept = append(kept, t)
+	}
+	return kept, nullable
+}
+
+// mergeAllOf folds the object subschemas of allOf into schema, as produced for a $ref
+// with sibling keywords.
+func mergeAllOf(schema map[string]interface{}) map[string]interface{} {
+	subschemas := sliceOf(schema["allOf"])
+	if len(subschemas) == 0 {
+		return schema
+	}
+	merged := map[string]interface{}{}
+	properties := map[string]interface{}{}
+	var required []interface{}
+	for i, sub := range append([]interface{}{schema}, subschemas...) {
+		subschema, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if _, nested := subschema["allOf"]; nested && i > 0 {
+			subschema = mergeAllOf(subschema)
+		}
+		for key, value := range subschema {
+			switch key {
+			case "allOf":
+			case "properties":
+				props, _ := value.(map[string]interface{})
+				for prop, propSchema := range props {
+					properties[prop] = propSchema
+				}
+			case "required":
+				required = append(required, sliceOf(value)...)
+			default:
+				if _, ok := merged[key]; !ok {
+					merged[key] = value
+				}
+			}
+		}
+	}
+	if len(properties) > 0 {
+		merged["properties"] = properties
+	}
+	if len(required) > 0 {
+		merged["required"] = required
+	}
+	return merged
+}
+
+// stringEnum returns the values of a string enum.
+func stringEnum(schema map[string]interface{}) ([]string, bool) {
+	enum := sliceOf(schema["enum"])
+	if len(enum) == 0 {
+		return nil, false
+	}
+	if types, _ := schemaTypes(schema); len(types) > 1 || (len(types) == 1 && types[0] != "string") {
+		return nil, false
+	}
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		values[i] = s
+	}
+	return values, true
+}
+
+func sliceOf(value interface{}) []interface{} {
+	items, _ := value.([]interface{})
+	return items
+}
+
+// pointerable reports whether a field of type t should become a pointer when optional.
+func pointerable(t string) bool {
+	return !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") && t != "interface{}"
+}
+
+var initialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// exportedName turns a schema or property name such as "main_subjects" into "MainSubjects".
+func exportedName(name string) string {
+	name = identifierPart(name)
+	if name == "" {
+		return "Field"
+	}
+	if first := []rune(name)[0]; !unicode.IsUpper(first) {
+		// Letters without case, such as Chinese, cannot start an exported name.
+		return "X" + name
+	}
+	return name
+}
+
+// identifierPart turns text into CamelCase words of letters and digits.
+func identifierPart(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, word := range words {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+	return b.String()
+}
+
+func writeComment(b *strings.Builder, indent, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString(indent)
+		b.WriteString(strings.TrimRight("// "+line, " "))
+		b.WriteString("\n")
+	}
+}
+
+// goString returns s as a raw string literal when possible.
+func goString(s string) string {
+	if strings.Contains(s, "`") || strings.Contains(s, "\r") {
+		return strconv.Quote(s)
+	}
+	return "`" + s + "`"
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rckyaml"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// generateOrders generates the types of testdata/order.json and the predefined scene_description schema.
+func generateOrders(t *testing.T) []byte {
+	t.Helper()
+	registry := rck.NewSchemaRegistry()
+	if err := rckyaml.LoadSchemaFile(registry, filepath.Join("testdata", "order.json")); err != nil {
+		t.Fatal(err)
+	}
+	g := newGenerator("orders", true)
+	targets := []struct {
+		schemas  *rck.SchemaRegistry
+		ref      string
+		typeName string
+	}{
+		{registry, "order", ""},
+		{rck.DefaultSchemas, "scene_description", "Scene"},
+	}
+	for _, target := range targets {
+		schema, err := target.schemas.SchemaMap(target.ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := g.add(target.ref, target.typeName, schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	source, err := g.source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return source
+}
+
+func TestGenerateGolden(t *testing.T) {
+	source := generateOrders(t)
+	golden := filepath.Join("testdata", "orders.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, source, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(source, want) {
+		t.Errorf("generated code differs from %s; run go test -update to accept it\n%s", golden, source)
+	}
+}
+
+func TestGeneratedCodeBuilds(t *testing.T) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "orders.go", generateOrders(t), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := config.Check("orders", fset, []*ast.File{file}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGenerateRejectsBadNames(t *testing.T) {
+	g := newGenerator("orders", false)
+	schema := map[string]interface{}{"type": "object"}
+	if err := g.add("order", "order", schema); err == nil {
+		t.Error("accepted an unexported type name")
+	}
+	if err := g.add("order", "", schema); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.add("order@v2", "", schema); err == nil {
+		t.Error("generated the same type twice")
+	}
+}
@@ -0,0 +1,125 @@
+// Command rck-gen generates Go types from OutputDataClass JSON Schemas.
+//
+// Each argument is a schema file (.json, .yaml or .yml, named as for
+// rckyaml.LoadSchemaDir) or the name of a registered schema such as
+// basic_analysis or translation@v2. Append "=TypeName" to choose the name of the
+// generated type; by default it is the schema name in CamelCase.
+//
+// For every schema rck-gen writes:
+//
+//   - a struct with json tags, doc comments taken from "description", and
+//     pointers for fields that are not required;
+//   - a string type with constants for every string "enum";
+//   - a <Type>Schema constant holding the schema, with references inlined;
+//   - a Transform<Type> function that calls Kernel.StructuredTransform with the
+//     schema and decodes the output into the generated type.
+//
+// It is meant to be run by go generate, which sets the package name:
+//
+//	//go:generate go run github.com/Askr-Omorsablin/rck-go-sdk/cmd/rck-gen -out rck_types.go schemas/customer.yaml basic_analysis=Analysis
+//
+// Usage:
+//
+//	rck-gen [-pkg name] [-out file] [-dir schemas] [-wrappers=false] schema...
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rckyaml"
+)
+
+func main() {
+	var (
+		pkg      = flag.String("pkg", os.Getenv("GOPACKAGE"), "package name of the generated file (defaults to $GOPACKAGE)")
+		outPath  = flag.String("out", "", "output file (defaults to standard output)")
+		dir      = flag.String("dir", "", "directory of schemas to load, so arguments and $ref can name them")
+		wrappers = flag.Bool("wrappers", true, "generate Transform<Type> functions")
+	)
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "usage: rck-gen [flags] schema[=TypeName]...")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("rck-gen: ")
+
+	if *pkg == "" || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	registry := rck.NewSchemaRegistry()
+	if *dir != "" {
+		if err := rckyaml.LoadSchemaDir(registry, *dir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var targets []target
+	for _, arg := range flag.Args() {
+		ref, typeName := arg, ""
+		if i := strings.LastIndex(arg, "="); i >= 0 {
+			ref, typeName = arg[:i], arg[i+1:]
+		}
+		if isSchemaFile(ref) {
+			if err := rckyaml.LoadSchemaFile(registry, ref); err != nil {
+				log.Fatal(err)
+			}
+			ref = strings.TrimSuffix(filepath.Base(ref), filepath.Ext(ref))
+		}
+		targets = append(targets, target{ref: ref, typeName: typeName})
+	}
+	if err := registry.Check(); err != nil {
+		log.Fatal(err)
+	}
+
+	g := newGenerator(*pkg, *wrappers)
+	for _, t := range targets {
+		schemas := registry
+		if !registry.Has(t.ref) {
+			schemas = rck.DefaultSchemas
+		}
+		schema, err := schemas.SchemaMap(t.ref)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := g.add(t.ref, t.typeName, schema); err != nil {
+			log.Fatalf("%s: %v", t.ref, err)
+		}
+	}
+
+	source, err := g.source()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *outPath == "" {
+		os.Stdout.Write(source)
+		return
+	}
+	if err := os.WriteFile(*outPath, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// target is a schema to generate a type for.
+type target struct {
+	ref      string
+	typeName string // Empty for the default name
+}
+
+func isSchemaFile(arg string) bool {
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".json", ".yaml", ".yml":
+	default:
+		return false
+	}
+	info, err := os.Stat(arg)
+	return err == nil && !info.IsDir()
+}
@@ -13,6 +13,10 @@ type StructuredTransformParams struct {
 	OutputDataClass interface{} // Can be a map[string]interface{} (JSON Schema) or a string
 	CustomLogic     map[string]string
 	Resource        []map[string]string
+	ValidateOutput  bool // Check the output against OutputDataClass and fail with a *SchemaViolationError
+	MaxRepairRounds int  // Re-issue the request with corrective feedback up to this many times when validation fails
+	// Templates renders prompts into FunctionLogic or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -26,6 +30,9 @@ func (p *StructuredTransformParams) Validate() error {
 	if p.OutputDataClass == nil {
 		return NewValidationError("OutputDataClass", "is required")
 	}
+	if p.MaxRepairRounds < 0 {
+		return NewValidationError("MaxRepairRounds", "must not be negative")
+	}
 	return nil
 }
 
@@ -33,8 +40,13 @@ func (p *StructuredTransformParams) Validate() error {
 type AnalyzeParams struct {
 	Input         string
 	FunctionLogic string
-	OutputFormat  string // Predefined schema name
+	OutputFormat  string // Schema name in the Kernel's SchemaRegistry, optionally "name@version"
 	CustomLogic   map[string]string
+	// ValidateOutput and MaxRepairRounds behave as in StructuredTransformParams.
+	ValidateOutput  bool
+	MaxRepairRounds int
+	// Templates renders prompts into FunctionLogic or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -48,9 +60,6 @@ func (p *AnalyzeParams) Validate() error {
 	if p.OutputFormat == "" {
 		return NewValidationError("OutputFormat", "is required")
 	}
-	if !HasSchema(p.OutputFormat) {
-		return NewValidationError("OutputFormat", "unknown schema name")
-	}
 	return nil
 }
 
@@ -59,6 +68,11 @@ type TranslateParams struct {
 	Input                string
 	TargetLanguage       string
 	IncludeCulturalNotes bool
+	// ValidateOutput and MaxRepairRounds behave as in StructuredTransformParams.
+	ValidateOutput  bool
+	MaxRepairRounds int
+	// Templates renders prompts into Input or TargetLanguage; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -78,6 +92,8 @@ type LearnFromExamplesParams struct {
 	Examples    []Example
 	CustomLogic map[string]string
 	Resource    []map[string]string
+	// Templates renders prompts into Input or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -97,6 +113,8 @@ type GenerateTextParams struct {
 	FunctionLogic string
 	CustomLogic   map[string]string
 	Resource      []map[string]string
+	// Templates renders prompts into FunctionLogic or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -121,6 +139,10 @@ type AutoParams struct {
 	FrameComposition string
 	Lighting         string
 	Style            string
+	// ClientRouting sends the engine chosen by Kernel.PlanAuto instead of letting the server choose.
+	ClientRouting bool
+	// Templates renders prompts into FunctionLogic, the image parameters or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -133,3 +155,25 @@ func (p *AutoParams) Validate() error {
 	}
 	return nil
 }
+
+// TransformParams are the parameters for a typed structured transformation.
+// The output schema is derived from the type argument passed to Transform.
+type TransformParams struct {
+	Input         string
+	FunctionLogic string
+	CustomLogic   map[string]string
+	Resource      []map[string]string
+	// Templates renders prompts into FunctionLogic or CustomLogic entries; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
+}
+
+// Validate checks if the parameters are valid.
+func (p *TransformParams) Validate() error {
+	if p.Input == "" {
+		return NewValidationError("Input", "is required")
+	}
+	if p.FunctionLogic == "" {
+		return NewValidationError("FunctionLogic", "is required")
+	}
+	return nil
+}
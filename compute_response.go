@@ -7,6 +7,10 @@ import (
 // ComputeResponse wraps the structured data returned by the compute API.
 type ComputeResponse struct {
 	rawData UnifiedAPIResponse
+	schema  string // OutputDataClass sent with the request, if any
+
+	repairRounds int
+	templates    []TemplateUse
 }
 
 // NewComputeResponse creates a response from the raw API output.
@@ -30,3 +34,23 @@ func (r *ComputeResponse) AsMap() (map[string]interface{}, error) {
 func (r *ComputeResponse) Raw() json.RawMessage {
 	return r.rawData.Output
 }
+
+// Validate checks the output against the OutputDataClass that was sent with the request.
+// It returns a *SchemaViolationError when the output does not conform.
+func (r *ComputeResponse) Validate() error {
+	if r.schema == "" {
+		return NewValidationError("OutputDataClass", "no schema was sent with this request")
+	}
+	return ValidateJSON(r.schema, r.rawData.Output)
+}
+
+// RepairRounds returns how many corrective re-requests were needed before the
+// output satisfied the schema. It is 0 when the first response was accepted.
+func (r *ComputeResponse) RepairRounds() int {
+	return r.repairRounds
+}
+
+// Templates returns the prompt templates that produced the request's parameters.
+func (r *ComputeResponse) Templates() []TemplateUse {
+	return r.templates
+}
@@ -0,0 +1,528 @@
+package rck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const defaultDAGConcurrency = 4
+
+// DAG is a workflow of SDK calls whose nodes run as soon as their dependencies finish,
+// independent nodes running concurrently. It can be written in JSON, or in YAML with
+// package rckyaml:
+//
+//	name: review
+//	concurrency: 3
+//	nodes:
+//	  - id: sentiment
+//	    operation: analyze
+//	    params: {FunctionLogic: "Analyze the tone", OutputFormat: basic_analysis}
+//	  - id: translation
+//	    operation: translate
+//	    params: {TargetLanguage: English}
+//	  - id: report
+//	    operation: generate_text
+//	    depends_on: [sentiment, translation]
+//	    input: "Tone: {{sentiment.emotion}}\nText: {{translation.translation}}"
+//	    params: {FunctionLogic: "Write a short report"}
+//	    on_error: default
+//	    default: "no report"
+//
+// Built-in operations are structured_transform, analyze, translate, learn_from_examples,
+// generate_text, generate_image and auto; their params decode into the matching Params type.
+// Other operations are Go functions added with Register.
+type DAG struct {
+	Name        string    `json:"name,omitempty" yaml:"name,omitempty"`
+	Concurrency int       `json:"concurrency,omitempty" yaml:"concurrency,omitempty"` // Maximum nodes running at once; defaults to 4
+	Nodes       []DAGNode `json:"nodes" yaml:"nodes"`
+
+	operations map[string]NodeFunc
+}
+
+// DAGNode is a single call in a DAG.
+//
+// The input of a node is, in order of precedence: its rendered Input template (see
+// StepOptions.Input; paths start with a dependency ID or "input"), the Input given in
+// Params, the output of its only dependency, the JSON object of its dependencies'
+// outputs keyed by ID, and otherwise the workflow input.
+type DAGNode struct {
+	ID        string                 `json:"id" yaml:"id"`
+	Operation string                 `json:"operation" yaml:"operation"`
+	DependsOn []string               `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	Input     string                 `json:"input,omitempty" yaml:"input,omitempty"`
+	Params    map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+	Config    *ComputeConfig         `json:"config,omitempty" yaml:"config,omitempty"`
+	OnError   ErrorPolicy            `json:"on_error,omitempty" yaml:"on_error,omitempty"`
+	Default   interface{}            `json:"default,omitempty" yaml:"default,omitempty"` // Output used with OnErrorDefault
+}
+
+// NodeInput is what a NodeFunc receives.
+type NodeInput struct {
+	Node     *DAGNode
+	Input    string
+	Upstream map[string]interface{} // Outputs of the node's dependencies, by node ID
+	Config   []ComputeConfig
+	Client   *Client
+}
+
+// DecodeParams decodes the node's Params into v, typically a Params struct.
+func (in NodeInput) DecodeParams(v interface{}) error {
+	data, err := json.Marshal(in.Node.Params)
+	if err != nil {
+		return NewValidationError("Params", err.Error())
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return NewValidationError("Params", fmt.Sprintf("node %q: %v", in.Node.ID, err))
+	}
+	return nil
+}
+
+// NodeFunc implements a DAG operation. Its result is recorded like a StepFunc result.
+type NodeFunc func(ctx context.Context, in NodeInput) (interface{}, error)
+
+var dagOperations = map[string]NodeFunc{
+	"structured_transform": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params StructuredTransformParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.StructuredTransform(ctx, params, in.Config...)
+	},
+	"analyze": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params AnalyzeParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.Analyze(ctx, params, in.Config...)
+	},
+	"translate": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params TranslateParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.Translate(ctx, params, in.Config...)
+	},
+	"learn_from_examples": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params LearnFromExamplesParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.LearnFromExamples(ctx, params, in.Config...)
+	},
+	"generate_text": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params GenerateTextParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.GenerateText(ctx, params, in.Config...)
+	},
+	"generate_image": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params GenerateParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Image.Generate(ctx, params)
+	},
+	"auto": func(ctx context.Context, in NodeInput) (interface{}, error) {
+		var params AutoParams
+		if err := in.DecodeParams(&params); err != nil {
+			return nil, err
+		}
+		params.Input = in.Input
+		return in.Client.Compute.Auto(ctx, params)
+	},
+}
+
+// ParseDAGJSON decodes a DAG written in JSON.
+func ParseDAGJSON(data []byte) (*DAG, error) {
+	var dag DAG
+	if err := json.Unmarshal(data, &dag); err != nil {
+		return nil, NewValidationError("DAG", fmt.Sprintf("invalid JSON: %v", err))
+	}
+	return &dag, nil
+}
+
+// JSON encodes the DAG as indented JSON. Registered functions are not encoded.
+func (d *DAG) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// Register makes a Go function available as an operation, replacing a built-in one of the same name.
+func (d *DAG) Register(operation string, fn NodeFunc) *DAG {
+	if d.operations == nil {
+		d.operations = map[string]NodeFunc{}
+	}
+	d.operations[operation] = fn
+	return d
+}
+
+func (d *DAG) operation(name string) (NodeFunc, bool) {
+	if fn, ok := d.operations[name]; ok {
+		return fn, true
+	}
+	fn, ok := dagOperations[name]
+	return fn, ok
+}
+
+// Validate checks node IDs, operations, dependencies and input templates, and that the graph has no cycle.
+func (d *DAG) Validate() error {
+	if d.Concurrency < 0 {
+		return NewValidationError("Concurrency", "must not be negative")
+	}
+	nodes := make(map[string]*DAGNode, len(d.Nodes))
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		switch {
+		case node.ID == "":
+			return NewValidationError("Nodes", fmt.Sprintf("node %d has no id", i))
+		case node.ID == "input":
+			return NewValidationError("Nodes", `node id "input" is reserved`)
+		case nodes[node.ID] != nil:
+			return NewValidationError("Nodes", fmt.Sprintf("duplicate node id %q", node.ID))
+		}
+		if _, ok := d.operation(node.Operation); !ok {
+			return NewValidationError("Nodes", fmt.Sprintf("node %q: unknown operation %q", node.ID, node.Operation))
+		}
+		nodes[node.ID] = node
+	}
+
+	for _, node := range d.Nodes {
+		deps := make(map[string]bool, len(node.DependsOn))
+		for _, dep := range node.DependsOn {
+			if dep == node.ID {
+				return NewValidationError("Nodes", fmt.Sprintf("node %q depends on itself", node.ID))
+			}
+			if nodes[dep] == nil {
+				return NewValidationError("Nodes", fmt.Sprintf("node %q depends on unknown node %q", node.ID, dep))
+			}
+			deps[dep] = true
+		}
+		roots, err := templateRoots(node.Input)
+		if err != nil {
+			return NewValidationError("Nodes", fmt.Sprintf("node %q: %v", node.ID, err))
+		}
+		for _, root := range roots {
+			if root != "input" && !deps[root] {
+				return NewValidationError("Nodes", fmt.Sprintf("node %q: input references %q, which is not a dependency", node.ID, root))
+			}
+		}
+	}
+
+	if cycle := d.findCycle(); cycle != nil {
+		return NewValidationError("Nodes", "dependency cycle: "+strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// findCycle returns the node IDs of a dependency cycle, first ID repeated at the end, or nil.
+func (d *DAG) findCycle() []string {
+	deps := make(map[string][]string, len(d.Nodes))
+	for _, node := range d.Nodes {
+		deps[node.ID] = node.DependsOn
+	}
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(d.Nodes))
+	var stack []string
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, dep := range deps[id] {
+			switch state[dep] {
+			case visiting:
+				for i, seen := range stack {
+					if seen == dep {
+						return append(append([]string(nil), stack[i:]...), dep)
+					}
+				}
+			case unvisited:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+		return nil
+	}
+	for _, node := range d.Nodes {
+		if state[node.ID] == unvisited {
+			if cycle := visit(node.ID); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// Run validates the DAG and executes it on input. Each node starts once all its dependencies
+// have finished. A node whose input needs the output of a skipped node is skipped too.
+// The returned run holds the trace of every node that ran, including when a node aborts
+// the workflow, in which case the error is a *DAGError and running nodes are canceled.
+// Nodes stopped by the abort or by canceling ctx get StepCanceled, whatever their OnError.
+func (d *DAG) Run(ctx context.Context, client *Client, input string) (*DAGRun, error) {
+	run := &DAGRun{Input: input, outputs: map[string]interface{}{}}
+	if err := d.Validate(); err != nil {
+		return run, err
+	}
+
+	concurrency := d.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultDAGConcurrency
+	}
+	slots := make(chan struct{}, concurrency)
+	nodeCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	pending := make(map[string]int, len(d.Nodes))
+	dependents := make(map[string][]*DAGNode, len(d.Nodes))
+	for i := range d.Nodes {
+		node := &d.Nodes[i]
+		pending[node.ID] = len(node.DependsOn)
+		for _, dep := range node.DependsOn {
+			dependents[dep] = append(dependents[dep], node)
+		}
+	}
+
+	done := make(chan StepTrace)
+	running := 0
+	launch := func(node *DAGNode) {
+		running++
+		in, inputErr := run.nodeInput(node, client)
+		fn, _ := d.operation(node.Operation)
+		go func() {
+			trace := StepTrace{Name: node.ID, Input: in.Input}
+			select {
+			case slots <- struct{}{}:
+			case <-nodeCtx.Done():
+				trace.Err = newNetworkError("workflow canceled", contextCause(nodeCtx), nodeCtx.Err())
+				done <- trace
+				return
+			}
+			defer func() { <-slots }()
+
+			start := time.Now()
+			trace.Err = inputErr
+			if trace.Err == nil {
+				var result interface{}
+				if result, trace.Err = fn(nodeCtx, in); trace.Err == nil {
+					trace.Result = result
+					trace.Output, trace.Err = stepOutput(result)
+				}
+			}
+			trace.Duration = time.Since(start)
+			done <- trace
+		}()
+	}
+
+	for i := range d.Nodes {
+		if pending[d.Nodes[i].ID] == 0 {
+			launch(&d.Nodes[i])
+		}
+	}
+
+	var abortErr error
+	for running > 0 {
+		trace := <-done
+		running--
+		node := d.node(trace.Name)
+
+		if trace.Err == nil {
+			trace.Status = StepSucceeded
+		} else {
+			switch {
+			case nodeCtx.Err() != nil && (errors.Is(trace.Err, context.Canceled) || errors.Is(trace.Err, context.DeadlineExceeded)):
+				// Stopped by an abort or by the caller, not by a failure of its own.
+				trace.Status = StepCanceled
+				trace.Output, trace.Result = nil, nil
+			case errors.Is(trace.Err, errDependencySkipped):
+				trace.Status = StepSkipped
+				trace.Output, trace.Result = nil, nil
+			case node.OnError == OnErrorSkip:
+				trace.Status = StepSkipped
+				trace.Output, trace.Result = nil, nil
+			case node.OnError == OnErrorDefault:
+				trace.Status = StepDefaulted
+				trace.Output, trace.Result = node.Default, node.Default
+			default:
+				trace.Status = StepFailed
+				trace.Output, trace.Result = nil, nil
+			}
+		}
+		run.record(trace)
+
+		if trace.Status == StepFailed {
+			if abortErr == nil {
+				abortErr = &DAGError{Node: node.ID, Err: trace.Err}
+				cancel()
+			}
+			continue
+		}
+		if abortErr != nil || trace.Status == StepCanceled {
+			continue
+		}
+		for _, dependent := range dependents[node.ID] {
+			if pending[dependent.ID]--; pending[dependent.ID] == 0 {
+				launch(dependent)
+			}
+		}
+	}
+
+	if abortErr != nil {
+		return run, abortErr
+	}
+	if err := ctx.Err(); err != nil {
+		return run, newNetworkError("workflow canceled", contextCause(ctx), err)
+	}
+	return run, nil
+}
+
+func (d *DAG) node(id string) *DAGNode {
+	for i := range d.Nodes {
+		if d.Nodes[i].ID == id {
+			return &d.Nodes[i]
+		}
+	}
+	return nil
+}
+
+// DAGRun is the state and trace of a DAG execution.
+type DAGRun struct {
+	Input string
+	Trace []StepTrace // Nodes in the order they finished
+
+	outputs map[string]interface{}
+}
+
+func (r *DAGRun) record(trace StepTrace) {
+	r.Trace = append(r.Trace, trace)
+	if trace.Status == StepSucceeded || trace.Status == StepDefaulted {
+		r.outputs[trace.Name] = trace.Output
+	}
+}
+
+// Output returns the output of a node, if it produced one.
+func (r *DAGRun) Output(id string) (interface{}, bool) {
+	output, ok := r.outputs[id]
+	return output, ok
+}
+
+// Outputs returns the outputs of every node that produced one, by node ID.
+func (r *DAGRun) Outputs() map[string]interface{} {
+	outputs := make(map[string]interface{}, len(r.outputs))
+	for id, output := range r.outputs {
+		outputs[id] = output
+	}
+	return outputs
+}
+
+// Node returns the trace of a node.
+func (r *DAGRun) Node(id string) (*StepTrace, bool) {
+	for i := range r.Trace {
+		if r.Trace[i].Name == id {
+			return &r.Trace[i], true
+		}
+	}
+	return nil, false
+}
+
+// errDependencySkipped marks a node skipped because the output it needs belongs to a skipped node.
+var errDependencySkipped = errors.New("dependency was skipped")
+
+// skipped reports whether the node with the given ID was skipped.
+func (r *DAGRun) skipped(id string) bool {
+	trace, ok := r.Node(id)
+	return ok && trace.Status == StepSkipped
+}
+
+// nodeInput builds the input of a node whose dependencies have all finished.
+func (r *DAGRun) nodeInput(node *DAGNode, client *Client) (NodeInput, error) {
+	in := NodeInput{Node: node, Upstream: make(map[string]interface{}, len(node.DependsOn)), Client: client}
+	for _, dep := range node.DependsOn {
+		if output, ok := r.outputs[dep]; ok {
+			in.Upstream[dep] = output
+		}
+	}
+	if node.Config != nil {
+		in.Config = []ComputeConfig{*node.Config}
+	}
+
+	lookup := func(path string) (interface{}, error) {
+		if root, _, err := splitPath(path); err == nil && r.skipped(root) {
+			return nil, fmt.Errorf("dependency %q: %w", root, errDependencySkipped)
+		}
+		return resolvePath(path, func(root string) (interface{}, bool) {
+			if root == "input" {
+				return r.Input, true
+			}
+			output, ok := in.Upstream[root]
+			return output, ok
+		})
+	}
+
+	var err error
+	switch {
+	case node.Input != "":
+		in.Input, err = renderTemplate(node.Input, lookup)
+	case paramsInput(node.Params) != "":
+		in.Input = paramsInput(node.Params)
+	case len(node.DependsOn) == 1:
+		output, ok := in.Upstream[node.DependsOn[0]]
+		if !ok {
+			if r.skipped(node.DependsOn[0]) {
+				return in, fmt.Errorf("dependency %q: %w", node.DependsOn[0], errDependencySkipped)
+			}
+			return in, fmt.Errorf("dependency %q has no output", node.DependsOn[0])
+		}
+		in.Input, err = formatValue(output)
+	case len(node.DependsOn) > 1:
+		in.Input, err = formatValue(in.Upstream)
+	default:
+		in.Input = r.Input
+	}
+	return in, err
+}
+
+// paramsInput returns the Input given in node params, matched case-insensitively like JSON field names.
+func paramsInput(params map[string]interface{}) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if text, ok := params[key].(string); ok && strings.EqualFold(key, "input") {
+			return text
+		}
+	}
+	return ""
+}
+
+// DAGError reports the node that aborted a DAG run.
+type DAGError struct {
+	Node string
+	Err  error
+}
+
+func (e *DAGError) Error() string {
+	return fmt.Sprintf("workflow node %q: %v", e.Node, e.Err)
+}
+
+func (e *DAGError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,135 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+func TestDAGPropagatesSkip(t *testing.T) {
+	var echoed atomic.Int64
+	dag := &rck.DAG{Nodes: []rck.DAGNode{
+		{ID: "source", Operation: "fail", OnError: rck.OnErrorSkip},
+		{ID: "implicit", Operation: "echo", DependsOn: []string{"source"}},
+		{ID: "templated", Operation: "echo", DependsOn: []string{"source"}, Input: "{{source.text}}"},
+		{ID: "downstream", Operation: "echo", DependsOn: []string{"implicit"}},
+		{ID: "independent", Operation: "echo"},
+	}}
+	dag.Register("fail", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	dag.Register("echo", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		echoed.Add(1)
+		return in.Input, nil
+	})
+
+	run, err := dag.Run(context.Background(), nil, "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]rck.StepStatus{
+		"source":      rck.StepSkipped,
+		"implicit":    rck.StepSkipped,
+		"templated":   rck.StepSkipped,
+		"downstream":  rck.StepSkipped,
+		"independent": rck.StepSucceeded,
+	} {
+		trace, ok := run.Node(id)
+		if !ok {
+			t.Errorf("node %s did not run", id)
+			continue
+		}
+		if trace.Status != want {
+			t.Errorf("node %s status = %s (%v), want %s", id, trace.Status, trace.Err, want)
+		}
+	}
+	if got := echoed.Load(); got != 1 {
+		t.Errorf("echo ran %d times, want only for the independent node", got)
+	}
+}
+
+func TestDAGFailureAborts(t *testing.T) {
+	dag := &rck.DAG{Nodes: []rck.DAGNode{
+		{ID: "source", Operation: "fail"},
+		{ID: "next", Operation: "fail", DependsOn: []string{"source"}},
+	}}
+	boom := errors.New("boom")
+	dag.Register("fail", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		return nil, boom
+	})
+
+	run, err := dag.Run(context.Background(), nil, "hello")
+	var dagErr *rck.DAGError
+	if !errors.As(err, &dagErr) || dagErr.Node != "source" || !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want a DAGError for source", err)
+	}
+	if _, ok := run.Node("next"); ok {
+		t.Error("dependent of a failed node ran")
+	}
+}
+
+func TestDAGAbortCancelsRunningNodes(t *testing.T) {
+	dag := &rck.DAG{Nodes: []rck.DAGNode{
+		{ID: "skippable", Operation: "wait", OnError: rck.OnErrorSkip},
+		{ID: "defaultable", Operation: "wait", OnError: rck.OnErrorDefault, Default: "fallback"},
+		{ID: "broken", Operation: "fail"},
+	}}
+	var started sync.WaitGroup
+	started.Add(2)
+	dag.Register("wait", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		started.Done()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	dag.Register("fail", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		started.Wait()
+		return nil, errors.New("boom")
+	})
+
+	run, err := dag.Run(context.Background(), nil, "hello")
+	var dagErr *rck.DAGError
+	if !errors.As(err, &dagErr) || dagErr.Node != "broken" {
+		t.Fatalf("error = %v, want a DAGError for broken", err)
+	}
+	for id, want := range map[string]rck.StepStatus{
+		"skippable":   rck.StepCanceled,
+		"defaultable": rck.StepCanceled,
+		"broken":      rck.StepFailed,
+	} {
+		trace, ok := run.Node(id)
+		if !ok || trace.Status != want {
+			t.Errorf("node %s = %+v, want status %s", id, trace, want)
+		}
+	}
+	if _, ok := run.Output("defaultable"); ok {
+		t.Error("canceled node got its default output")
+	}
+}
+
+func TestDAGCallerCancelCancelsNodes(t *testing.T) {
+	dag := &rck.DAG{Nodes: []rck.DAGNode{
+		{ID: "waiting", Operation: "wait", OnError: rck.OnErrorSkip},
+		{ID: "next", Operation: "wait", DependsOn: []string{"waiting"}},
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	dag.Register("wait", func(ctx context.Context, in rck.NodeInput) (interface{}, error) {
+		cancel()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	run, err := dag.Run(ctx, nil, "hello")
+	if !errors.Is(err, rck.CauseCanceled) {
+		t.Fatalf("error = %v, want CauseCanceled", err)
+	}
+	if trace, ok := run.Node("waiting"); !ok || trace.Status != rck.StepCanceled {
+		t.Errorf("waiting = %+v, want status canceled", trace)
+	}
+	if _, ok := run.Node("next"); ok {
+		t.Error("dependent of a canceled node ran")
+	}
+}
@@ -0,0 +1,96 @@
+package rck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightGroup shares one HTTP call among concurrent identical requests.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a call in progress and the callers waiting for it.
+type flight struct {
+	done     chan struct{}
+	response *UnifiedAPIResponse
+	err      error
+	waiters  int
+	cancel   context.CancelFunc
+	deadline time.Time // The first caller's deadline; zero for none
+}
+
+// outlives reports whether the flight may run at least as long as ctx.
+func (f *flight) outlives(ctx context.Context) bool {
+	if f.deadline.IsZero() {
+		return true
+	}
+	deadline, ok := ctx.Deadline()
+	return ok && !deadline.After(f.deadline)
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do runs send once for all concurrent callers with the same key and gives each the result.
+// The shared call runs on a context with the first caller's values and deadline but not its
+// cancellation, and keeps running while at least one caller waits; it is canceled when the
+// last one gives up. A caller whose deadline is later than the call's starts a new call, so
+// that no caller times out early.
+func (g *flightGroup) do(ctx context.Context, key string, send func(ctx context.Context) (*UnifiedAPIResponse, error)) (*UnifiedAPIResponse, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if !ok || !f.outlives(ctx) {
+		sharedCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		if deadline, ok := ctx.Deadline(); ok {
+			var cancelDeadline context.CancelFunc
+			sharedCtx, cancelDeadline = context.WithDeadline(sharedCtx, deadline)
+			f.deadline = deadline
+			f.cancel = func() {
+				cancelDeadline()
+				cancel()
+			}
+		}
+		g.flights[key] = f
+		go func() {
+			f.response, f.err = send(sharedCtx)
+			g.mu.Lock()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			g.mu.Unlock()
+			f.cancel()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		if f.err != nil {
+			return nil, f.err
+		}
+		return copyResponse(f.response), nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			// Nobody is left to receive the result: stop the call and let the next caller start afresh.
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+			f.cancel()
+		}
+		g.mu.Unlock()
+		message := "request canceled"
+		if contextCause(ctx) == CauseDeadline {
+			message = "request timeout"
+		}
+		return nil, newNetworkError(message, contextCause(ctx), ctx.Err())
+	}
+}
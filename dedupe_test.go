@@ -0,0 +1,102 @@
+package rck_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func newDedupeClient(t *testing.T, latency time.Duration) (*rck.Client, *rcktest.Server) {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetLatency(latency)
+	options := server.ClientOptions()
+	options.Deduplicate = true
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+func TestDeduplicateSharesOneCall(t *testing.T) {
+	client, server := newDedupeClient(t, 100*time.Millisecond)
+	params := rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := client.Compute.GenerateText(context.Background(), params)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.RequestCount(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestDeduplicateKeepsLongerDeadline(t *testing.T) {
+	client, server := newDedupeClient(t, 150*time.Millisecond)
+	params := rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"}
+
+	short, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	shortErr := make(chan error, 1)
+	go func() {
+		_, err := client.Compute.GenerateText(short, params)
+		shortErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	// Joining the short call would make this one time out with it.
+	if _, err := client.Compute.GenerateText(context.Background(), params); err != nil {
+		t.Errorf("caller without deadline failed: %v", err)
+	}
+	if err := <-shortErr; err == nil {
+		t.Error("caller with a short deadline succeeded")
+	}
+	if got := server.RequestCount(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+}
+
+func TestDeduplicateSeparatesTemplates(t *testing.T) {
+	client, server := newDedupeClient(t, 100*time.Millisecond)
+	tmpl, err := rck.ParsePromptTemplate("echo", "v1", "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"}
+	templated := plain
+	templated.Templates = map[string]*rck.Prompt{"FunctionLogic": tmpl.With(nil)}
+
+	var wg sync.WaitGroup
+	for _, params := range []rck.GenerateTextParams{plain, templated} {
+		wg.Add(1)
+		go func(params rck.GenerateTextParams) {
+			defer wg.Done()
+			if _, err := client.Compute.GenerateText(context.Background(), params); err != nil {
+				t.Error(err)
+			}
+		}(params)
+	}
+	wg.Wait()
+	if got := server.RequestCount(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+}
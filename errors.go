@@ -3,12 +3,36 @@ package rck
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrorKind classifies failures so callers can react without inspecting messages.
+type ErrorKind string
+
+// Error kinds reported by APIError and NetworkError.
+const (
+	KindAPI               ErrorKind = "api_error"          // Any other error response
+	KindAuthentication    ErrorKind = "authentication"     // 401/403: missing or invalid API key
+	KindRateLimited       ErrorKind = "rate_limited"       // 429: too many requests, retry later
+	KindQuotaExceeded     ErrorKind = "quota_exceeded"     // 402, or 429 mentioning quota: usage exhausted
+	KindInvalidProgram    ErrorKind = "invalid_program"    // 400/422: the server rejected the program
+	KindEngineUnavailable ErrorKind = "engine_unavailable" // 503: the engine cannot serve requests right now
+	KindServer            ErrorKind = "server_error"       // Other 5xx responses
+	KindInvalidResponse   ErrorKind = "invalid_response"   // The response did not have the expected format
+	KindNetwork           ErrorKind = "network"            // Transport failure
+	KindTimeout           ErrorKind = "timeout"            // The caller's deadline or ClientOptions.Timeout expired; see NetworkCause
+	KindCanceled          ErrorKind = "canceled"           // The caller canceled the request
 )
 
 // APIError represents an error returned from the RCK API.
 type APIError struct {
 	StatusCode   int
 	ResponseData *UnifiedAPIResponse
+	Kind         ErrorKind
+	Header       http.Header // Response headers
+	RequestID    string      // Server-assigned request ID, if reported
+	Body         []byte      // Raw response body
 }
 
 func (e *APIError) Error() string {
@@ -18,6 +42,22 @@ func (e *APIError) Error() string {
 	return fmt.Sprintf("API error with status code %d", e.StatusCode)
 }
 
+// Is reports whether target is a sentinel of the same kind, so that
+// errors.Is(err, ErrRateLimited) matches any rate-limit response.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Kind != "" && t.Kind == e.Kind
+}
+
+// Retryable reports whether the same request may succeed if sent again later.
+func (e *APIError) Retryable() bool {
+	switch e.Kind {
+	case KindRateLimited, KindEngineUnavailable, KindServer:
+		return true
+	}
+	return e.StatusCode == http.StatusRequestTimeout
+}
+
 // ValidationError represents an error in validating request parameters.
 type ValidationError struct {
 	Field   string
@@ -32,13 +72,49 @@ func (e *ValidationError) Error() string {
 type NetworkError struct {
 	Message       string
 	OriginalError error
+	Kind          ErrorKind     // KindNetwork, KindTimeout, KindCanceled or KindInvalidResponse; empty for local failures
+	Cause         NetworkCause  // Where the failure happened
+	Elapsed       time.Duration // Time spent on the call, including retries
+	Attempts      int           // Number of HTTP attempts made
 }
 
 func (e *NetworkError) Error() string {
+	msg := "network error: " + e.Message
 	if e.OriginalError != nil {
-		return fmt.Sprintf("network error: %s (caused by: %v)", e.Message, e.OriginalError)
+		msg = fmt.Sprintf("%s (caused by: %v)", msg, e.OriginalError)
+	}
+	if e.Attempts > 1 {
+		msg = fmt.Sprintf("%s after %d attempts in %s", msg, e.Attempts, e.Elapsed.Round(time.Millisecond))
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error, so errors.Is(err, context.Canceled) and
+// errors.As(err, &dnsErr) see through the NetworkError.
+func (e *NetworkError) Unwrap() error {
+	return e.OriginalError
+}
+
+// Is reports whether target is a sentinel of the same kind, e.g. ErrTimeout or
+// ErrInvalidResponse, or the NetworkCause of this error, e.g. CauseDeadline.
+func (e *NetworkError) Is(target error) bool {
+	switch t := target.(type) {
+	case *NetworkError:
+		return t.Kind != "" && t.Kind == e.Kind
+	case *APIError:
+		return t.Kind != "" && t.Kind == e.Kind
+	case NetworkCause:
+		return t == e.Cause
+	}
+	return false
+}
+
+// Retryable reports whether the same request may succeed if sent again.
+func (e *NetworkError) Retryable() bool {
+	if e.Cause != "" {
+		return e.Cause.retryable()
 	}
-	return fmt.Sprintf("network error: %s", e.Message)
+	return e.Kind == KindNetwork || e.Kind == KindTimeout
 }
 
 // UnmarshalJSON implements the json.Unmarshaler interface for NetworkError
@@ -52,10 +128,19 @@ func (e *NetworkError) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
-// Predefined error instances
+// Predefined error instances. Errors returned by the SDK match them with errors.Is.
+// Authentication failures are returned as ErrAuthentication itself, without response metadata.
 var (
-	ErrAuthentication = &APIError{StatusCode: 401}
-	ErrAPIKeyRequired = &ValidationError{Field: "APIKey", Message: "API key is required"}
+	ErrAuthentication    = &APIError{StatusCode: 401, Kind: KindAuthentication}
+	ErrRateLimited       = &APIError{StatusCode: 429, Kind: KindRateLimited}
+	ErrQuotaExceeded     = &APIError{StatusCode: 402, Kind: KindQuotaExceeded}
+	ErrInvalidProgram    = &APIError{StatusCode: 400, Kind: KindInvalidProgram}
+	ErrEngineUnavailable = &APIError{StatusCode: 503, Kind: KindEngineUnavailable}
+	ErrServerError       = &APIError{StatusCode: 500, Kind: KindServer}
+	ErrInvalidResponse   = &APIError{StatusCode: 200, Kind: KindInvalidResponse}
+	ErrTimeout           = &NetworkError{Message: "request timeout", Kind: KindTimeout}
+	ErrCanceled          = &NetworkError{Message: "request canceled", Kind: KindCanceled}
+	ErrAPIKeyRequired    = &ValidationError{Field: "APIKey", Message: "API key is required"}
 )
 
 // NewValidationError creates a new validation error.
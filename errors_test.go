@@ -0,0 +1,60 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func generateText(t *testing.T, server *rcktest.Server, apiKey string) error {
+	t.Helper()
+	options := server.ClientOptions()
+	options.Retry = &rck.RetryPolicy{MaxAttempts: 1}
+	client, err := rck.NewClient(apiKey, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Compute.GenerateText(context.Background(), rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"})
+	return err
+}
+
+func TestAuthenticationErrorIsSentinel(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.RequireAPIKey("right-key")
+
+	err := generateText(t, server, "wrong-key")
+	if err != rck.ErrAuthentication {
+		t.Errorf("error = %#v, want ErrAuthentication itself", err)
+	}
+}
+
+func TestErrorsMatchSentinels(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(*rcktest.Server)
+		sentinel error
+	}{
+		{"rate limited", func(s *rcktest.Server) { s.FailNext(1, http.StatusTooManyRequests) }, rck.ErrRateLimited},
+		{"engine unavailable", func(s *rcktest.Server) { s.FailNext(1, http.StatusServiceUnavailable) }, rck.ErrEngineUnavailable},
+		{"server error", func(s *rcktest.Server) { s.FailNext(1, http.StatusInternalServerError) }, rck.ErrServerError},
+		{"invalid program", func(s *rcktest.Server) { s.FailNext(1, http.StatusBadRequest) }, rck.ErrInvalidProgram},
+		{"invalid response", func(s *rcktest.Server) { s.MalformNext(1) }, rck.ErrInvalidResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rcktest.NewServer()
+			defer server.Close()
+			tt.setup(server)
+
+			err := generateText(t, server, "test-key")
+			if !errors.Is(err, tt.sentinel) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.sentinel)
+			}
+		})
+	}
+}
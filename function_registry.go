@@ -0,0 +1,256 @@
+package rck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// FunctionDefinition is a named, versioned RCK program that can be called with just an input.
+// In files the fields are written in snake_case, here in YAML as read by package rckyaml:
+//
+//	name: extract_customer
+//	version: v3
+//	function_logic: Extract the customer's name and phone number
+//	output_data_class:
+//	  type: object
+//	  properties:
+//	    name: {type: string}
+//	    phone: {type: string}
+//	config: {temperature: 0}
+type FunctionDefinition struct {
+	Name            string            `json:"name" yaml:"name"`
+	Version         string            `json:"version,omitempty" yaml:"version,omitempty"`
+	Description     string            `json:"description,omitempty" yaml:"description,omitempty"`
+	FunctionLogic   string            `json:"function_logic,omitempty" yaml:"function_logic,omitempty"`
+	OutputDataClass interface{}       `json:"output_data_class,omitempty" yaml:"output_data_class,omitempty"` // JSON Schema as a map or string
+	CustomLogic     map[string]string `json:"custom_logic,omitempty" yaml:"custom_logic,omitempty"`
+	Examples        []Example         `json:"examples,omitempty" yaml:"examples,omitempty"`
+	Config          *ComputeConfig    `json:"config,omitempty" yaml:"config,omitempty"` // Default compute configuration
+
+	// Engine runs the function; by default standard when OutputDataClass is set,
+	// attractor when Examples are set, and pure otherwise.
+	Engine Engine `json:"engine,omitempty" yaml:"engine,omitempty"`
+}
+
+// Ref returns "name@version", or the name alone for an unversioned function.
+func (d *FunctionDefinition) Ref() string {
+	return promptRef(d.Name, d.Version)
+}
+
+func (d *FunctionDefinition) engine() Engine {
+	switch {
+	case d.Engine != "":
+		return d.Engine
+	case d.OutputDataClass != nil:
+		return EngineStandard
+	case len(d.Examples) > 0:
+		return EngineAttractor
+	default:
+		return EnginePure
+	}
+}
+
+// Validate checks if the definition is complete for its engine.
+func (d *FunctionDefinition) Validate() error {
+	if d.Name == "" {
+		return NewValidationError("Name", "is required")
+	}
+	if strings.Contains(d.Name, "@") || strings.Contains(d.Version, "@") {
+		return NewValidationError("Name", fmt.Sprintf("%s: name and version must not contain '@'", d.Ref()))
+	}
+	switch d.engine() {
+	case EngineStandard:
+		if d.FunctionLogic == "" {
+			return NewValidationError("FunctionLogic", fmt.Sprintf("%s: is required by the standard engine", d.Ref()))
+		}
+		if d.OutputDataClass == nil {
+			return NewValidationError("OutputDataClass", fmt.Sprintf("%s: is required by the standard engine", d.Ref()))
+		}
+		if _, err := stringifyOutputClass(d.OutputDataClass); err != nil {
+			return err
+		}
+	case EngineAttractor:
+		if len(d.Examples) == 0 {
+			return NewValidationError("Examples", fmt.Sprintf("%s: the attractor engine requires at least one example", d.Ref()))
+		}
+	case EnginePure, EngineAuto:
+		if d.FunctionLogic == "" && len(d.Examples) == 0 && d.OutputDataClass == nil {
+			return NewValidationError("FunctionLogic", fmt.Sprintf("%s: is required", d.Ref()))
+		}
+	default:
+		return NewValidationError("Engine", fmt.Sprintf("%s: unsupported engine %q", d.Ref(), d.Engine))
+	}
+	return nil
+}
+
+// program builds the program calling the function on input.
+func (d *FunctionDefinition) program(input string) (APIProgram, error) {
+	pipeline := APIPipeline{
+		FunctionName:  d.Ref(),
+		FunctionLogic: d.FunctionLogic,
+		CustomLogic:   d.CustomLogic,
+	}
+	if d.OutputDataClass != nil {
+		outputClassStr, err := stringifyOutputClass(d.OutputDataClass)
+		if err != nil {
+			return APIProgram{}, err
+		}
+		pipeline.OutputDataClass = outputClassStr
+	}
+	for i, ex := range d.Examples {
+		outputBytes, err := json.Marshal(ex.Output)
+		if err != nil {
+			return APIProgram{}, fmt.Errorf("failed to marshal example output at index %d: %w", i, err)
+		}
+		pipeline.Examples = append(pipeline.Examples, APIExample{Input: ex.Input, Output: string(outputBytes)})
+	}
+	return APIProgram{Input: APIInput{Input: input}, Pipeline: pipeline}, nil
+}
+
+// FunctionRegistry holds function definitions by name and version and calls them through a Kernel.
+// It is safe for concurrent use.
+type FunctionRegistry struct {
+	kernel *Kernel
+
+	mu        sync.RWMutex
+	functions versioned[*FunctionDefinition]
+}
+
+// NewFunctionRegistry creates an empty registry whose calls run on kernel.
+func NewFunctionRegistry(kernel *Kernel) *FunctionRegistry {
+	return &FunctionRegistry{
+		kernel:    kernel,
+		functions: newVersioned("function", func(d *FunctionDefinition) string { return d.Version }),
+	}
+}
+
+// Register validates and stores a definition. A name and version can be registered only once.
+func (r *FunctionRegistry) Register(def FunctionDefinition) error {
+	if err := def.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.functions.add(def.Name, &def) {
+		return NewValidationError("Name", fmt.Sprintf("%s is already registered", def.Ref()))
+	}
+	return nil
+}
+
+// LoadDir registers the definitions of every .json file in dir. A file holds a single
+// definition or a list of them. Package rckyaml loads YAML files as well.
+func (r *FunctionRegistry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS is like LoadDir for a directory of an fs.FS, such as an embed.FS.
+func (r *FunctionRegistry) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if entry.IsDir() || ext != ".json" {
+			continue
+		}
+		name := path.Join(dir, entry.Name())
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		defs, err := decodeFunctionDefinitions(data)
+		if err != nil {
+			return NewValidationError("FunctionDefinition", fmt.Sprintf("%s: %v", name, err))
+		}
+		for _, def := range defs {
+			if err := r.Register(def); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func decodeFunctionDefinitions(data []byte) ([]FunctionDefinition, error) {
+	var defs []FunctionDefinition
+	if err := json.Unmarshal(data, &defs); err == nil {
+		return defs, nil
+	}
+	var def FunctionDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	return []FunctionDefinition{def}, nil
+}
+
+// Get returns the definition for "name@version", or the latest version for a bare name.
+func (r *FunctionRegistry) Get(ref string) (*FunctionDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, err := r.functions.get(ref)
+	if err != nil {
+		return nil, NewValidationError("Function", err.Error())
+	}
+	return def, nil
+}
+
+// Names returns the names of the registered functions in sorted order.
+func (r *FunctionRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.functions.names()
+}
+
+// Call runs a registered function on input. The reference is "name@version" or a bare name for
+// the latest version. The request's FunctionName is set to the resolved "name@version", and
+// fields set in config override the definition's default configuration.
+func (r *FunctionRegistry) Call(ctx context.Context, ref string, input string, config ...ComputeConfig) (*ComputeResponse, error) {
+	if input == "" {
+		return nil, NewValidationError("Input", "is required")
+	}
+	def, err := r.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	program, err := def.program(input)
+	if err != nil {
+		return nil, err
+	}
+
+	apiConfig := &APIConfig{Engine: def.engine()}
+	if def.Config != nil {
+		apiConfig.ComputeConfig = *def.Config
+	}
+	if len(config) > 0 {
+		apiConfig.ComputeConfig = mergeComputeConfig(apiConfig.ComputeConfig, config[0])
+	}
+
+	response, err := r.kernel.execute(ctx, program, apiConfig)
+	if err != nil {
+		return nil, err
+	}
+	result := NewComputeResponse(*response)
+	result.schema = program.Pipeline.OutputDataClass
+	return result, nil
+}
+
+// mergeComputeConfig returns base with the fields set in override replaced.
+func mergeComputeConfig(base, override ComputeConfig) ComputeConfig {
+	if override.Speed != "" {
+		base.Speed = override.Speed
+	}
+	if override.Scale != "" {
+		base.Scale = override.Scale
+	}
+	if override.Temperature != nil {
+		base.Temperature = override.Temperature
+	}
+	return base
+}
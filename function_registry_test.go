@@ -0,0 +1,152 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func newFunctionRegistry(t *testing.T) (*rck.FunctionRegistry, *rcktest.Server) {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rck.NewFunctionRegistry(client.Compute), server
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	registry, _ := newFunctionRegistry(t)
+	for _, version := range []string{"v2", "v10", "v1"} {
+		if err := registry.Register(rck.FunctionDefinition{Name: "summarize", Version: version, FunctionLogic: "Summarize " + version}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	invalid := []rck.FunctionDefinition{
+		{Name: "summarize", Version: "v2", FunctionLogic: "again"},
+		{FunctionLogic: "no name"},
+		{Name: "a@b", FunctionLogic: "bad name"},
+		{Name: "extract", OutputDataClass: map[string]interface{}{"type": "object"}},
+		{Name: "classify", Engine: rck.EngineAttractor},
+		{Name: "empty"},
+	}
+	for _, def := range invalid {
+		var validation *rck.ValidationError
+		if err := registry.Register(def); !errors.As(err, &validation) {
+			t.Errorf("Register(%+v) = %v, want a ValidationError", def, err)
+		}
+	}
+	if names := registry.Names(); len(names) != 1 || names[0] != "summarize" {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestFunctionRegistryGet(t *testing.T) {
+	registry, _ := newFunctionRegistry(t)
+	for _, version := range []string{"v2", "v10", "v1"} {
+		if err := registry.Register(rck.FunctionDefinition{Name: "summarize", Version: version, FunctionLogic: "Summarize"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		ref     string
+		version string
+		ok      bool
+	}{
+		{"summarize", "v10", true},
+		{"summarize@v2", "v2", true},
+		{"summarize@v3", "", false},
+		{"unknown", "", false},
+	}
+	for _, tt := range tests {
+		def, err := registry.Get(tt.ref)
+		if !tt.ok {
+			var validation *rck.ValidationError
+			if !errors.As(err, &validation) {
+				t.Errorf("Get(%q) = %v, want a ValidationError", tt.ref, err)
+			}
+			continue
+		}
+		if err != nil || def.Version != tt.version {
+			t.Errorf("Get(%q) = %+v, %v, want version %s", tt.ref, def, err, tt.version)
+		}
+	}
+}
+
+func TestFunctionRegistryLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"extract.json": `{"name": "extract", "version": "v1", "function_logic": "Extract", "output_data_class": {"type": "object"}}`,
+		"many.json":    `[{"name": "a", "function_logic": "A"}, {"name": "b", "function_logic": "B"}]`,
+		"notes.yaml":   `name: ignored`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	registry, _ := newFunctionRegistry(t)
+	if err := registry.LoadDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if names := registry.Names(); len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "extract" {
+		t.Errorf("names = %v", names)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte(`{"name": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := rck.NewFunctionRegistry(nil).LoadDir(dir); err == nil {
+		t.Error("LoadDir accepted a broken file")
+	}
+}
+
+func TestFunctionRegistryCall(t *testing.T) {
+	registry, server := newFunctionRegistry(t)
+	defaultTemperature, override := 0.0, 0.5
+	err := registry.Register(rck.FunctionDefinition{
+		Name:            "extract",
+		Version:         "v3",
+		FunctionLogic:   "Extract the name",
+		OutputDataClass: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}}},
+		Config:          &rck.ComputeConfig{Speed: rck.SpeedFast, Temperature: &defaultTemperature},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := registry.Call(context.Background(), "extract", "Ada called", rck.ComputeConfig{Temperature: &override}); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(requests))
+	}
+	request := requests[0]
+	if request.Program.Pipeline.FunctionName != "extract@v3" {
+		t.Errorf("FunctionName = %q, want extract@v3", request.Program.Pipeline.FunctionName)
+	}
+	if request.Program.Input.Input != "Ada called" || request.Program.Pipeline.FunctionLogic != "Extract the name" {
+		t.Errorf("program = %+v", request.Program)
+	}
+	config := request.Config
+	if config.Engine != rck.EngineStandard || config.Speed != rck.SpeedFast || config.Temperature == nil || *config.Temperature != override {
+		t.Errorf("config = %+v, want the standard engine, fast speed and the overriding temperature", config)
+	}
+
+	if _, err := registry.Call(context.Background(), "extract", ""); err == nil {
+		t.Error("Call accepted an empty input")
+	}
+	if _, err := registry.Call(context.Background(), "missing", "x"); err == nil {
+		t.Error("Call found an unknown function")
+	}
+}
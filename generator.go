@@ -20,6 +20,10 @@ func NewGenerator(client *HttpClient) *Generator {
 
 // Generate creates images based on the provided parameters.
 func (g *Generator) Generate(ctx context.Context, params GenerateParams) (*ImageResponse, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
@@ -41,11 +45,17 @@ func (g *Generator) Generate(ctx context.Context, params GenerateParams) (*Image
 		return nil, err
 	}
 
+	return decodeImageResponse(rawResponse)
+}
+
+// decodeImageResponse reads the list of image URLs returned by the image engine.
+func decodeImageResponse(rawResponse *UnifiedAPIResponse) (*ImageResponse, error) {
 	var output []string
 	if err := json.Unmarshal(rawResponse.Output, &output); err != nil {
 		return nil, &APIError{
 			StatusCode:   200, // Assuming 200 OK but bad format
 			ResponseData: rawResponse,
+			Kind:         KindInvalidResponse,
 		}
 	}
 	return NewImageResponse(output, *rawResponse), nil
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -16,6 +17,10 @@ type HttpClient struct {
 	apiKey     string
 	baseURL    string
 	httpClient *http.Client
+	retry      *RetryPolicy
+	middleware []Middleware
+	limiter    *rateLimiter
+	flights    *flightGroup
 }
 
 // NewHttpClient creates a new instance of the HttpClient.
@@ -29,64 +34,358 @@ func NewHttpClient(apiKey, baseURL string, timeout time.Duration) *HttpClient {
 	}
 }
 
-// Post sends a POST request to the specified endpoint.
+// SetRetryPolicy sets the policy used to retry failed requests. A nil policy disables retries.
+func (c *HttpClient) SetRetryPolicy(policy *RetryPolicy) {
+	c.retry = policy
+}
+
+// SetHTTPClient replaces the underlying *http.Client, e.g. to configure a proxy, TLS roots or connection pooling.
+func (c *HttpClient) SetHTTPClient(client *http.Client) {
+	c.httpClient = client
+}
+
+// SetRateLimit installs a client-side limiter applied to every request attempt. Nil removes it.
+func (c *HttpClient) SetRateLimit(options *RateLimitOptions) {
+	c.limiter = newRateLimiter(options)
+}
+
+// LimiterStats returns wait statistics of the client-side limiter.
+func (c *HttpClient) LimiterStats() LimiterStats {
+	return c.limiter.snapshot()
+}
+
+// SetDeduplicate makes concurrent identical requests share a single HTTP call, run once
+// through middleware, whose result or error every caller receives.
+func (c *HttpClient) SetDeduplicate(enabled bool) {
+	if enabled {
+		c.flights = newFlightGroup()
+	} else {
+		c.flights = nil
+	}
+}
+
+// Use appends middleware to the chain run around every call. The first middleware added is the outermost.
+func (c *HttpClient) Use(middleware ...Middleware) {
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// attemptResult is the outcome of a single HTTP round trip.
+type attemptResult struct {
+	statusCode int
+	header     http.Header
+	err        error
+	transient  bool // The failure happened in transport and may succeed on retry
+}
+
+// Post sends a POST request to the specified endpoint through the middleware chain,
+// retrying according to the client's RetryPolicy.
 func (c *HttpClient) Post(ctx context.Context, endpoint string, payload *UnifiedAPIRequest) (*UnifiedAPIResponse, error) {
-	jsonData, err := json.Marshal(payload)
+	if c.flights != nil {
+		if key, err := CacheKey(payload); err == nil {
+			// Templates become a header, so calls built from different templates are not shared.
+			for _, use := range templateUses(ctx) {
+				key += " " + use.String()
+			}
+			return c.flights.do(ctx, endpoint+" "+key, func(ctx context.Context) (*UnifiedAPIResponse, error) {
+				return c.post(ctx, endpoint, payload)
+			})
+		}
+	}
+	return c.post(ctx, endpoint, payload)
+}
+
+func (c *HttpClient) post(ctx context.Context, endpoint string, payload *UnifiedAPIRequest) (*UnifiedAPIResponse, error) {
+	call := newCall(ctx, endpoint, payload)
+	return chainMiddleware(c.send, c.middleware)(ctx, call)
+}
+
+// send is the innermost Invoker: it performs the HTTP round trips for a call.
+func (c *HttpClient) send(ctx context.Context, call *Call) (*UnifiedAPIResponse, error) {
+	jsonData, err := json.Marshal(call.Request)
 	if err != nil {
-		return nil, &NetworkError{Message: "failed to marshal request payload", OriginalError: err}
+		return nil, newNetworkError("failed to marshal request payload", CauseLocal, err)
+	}
+
+	var apiResponse UnifiedAPIResponse
+	request := rawRequest{method: http.MethodPost, endpoint: call.Endpoint, header: call.Header, body: jsonData, engine: call.engine()}
+	if err := c.roundTrip(ctx, request, &apiResponse); err != nil {
+		return nil, err
+	}
+	return &apiResponse, nil
+}
+
+// promptTemplatesHeader lists the prompt templates that produced a request, as "field=name@version" items.
+const promptTemplatesHeader = "X-RCK-Prompt-Templates"
+
+// newCall describes a call of payload, including the prompt templates recorded on ctx.
+func newCall(ctx context.Context, endpoint string, payload *UnifiedAPIRequest) *Call {
+	call := &Call{Endpoint: endpoint, Request: payload, Header: http.Header{}, Templates: templateUses(ctx)}
+	if len(call.Templates) > 0 {
+		uses := make([]string, len(call.Templates))
+		for i, use := range call.Templates {
+			uses[i] = use.String()
+		}
+		call.Header.Set(promptTemplatesHeader, strings.Join(uses, ", "))
+	}
+	return call
+}
+
+// rawRequest is a prepared HTTP request that can be sent several times.
+type rawRequest struct {
+	method   string
+	endpoint string
+	header   http.Header
+	body     []byte // JSON body, nil for none
+	engine   Engine // Engine used for rate limiting; "" applies only the global limit
+}
+
+// roundTrip sends a request, retrying according to the client's RetryPolicy, and decodes the response into out.
+// Unlike Post it does not run middleware, which only see program calls.
+func (c *HttpClient) roundTrip(ctx context.Context, request rawRequest, out interface{}) error {
+	start := time.Now()
+	maxAttempts := c.retry.maxAttempts()
+	for attempt := 1; ; attempt++ {
+		release, err := c.limiter.acquire(ctx, request.engine)
+		if err != nil {
+			return finishNetworkError(limiterError(ctx, err), start, attempt-1)
+		}
+		result := c.doOnce(ctx, request, out)
+		release()
+		if result.err == nil {
+			return nil
+		}
+		if attempt >= maxAttempts || !c.shouldRetry(ctx, result) {
+			return finishNetworkError(result.err, start, attempt)
+		}
+
+		if !sleepContext(ctx, c.retry.delay(attempt, result.header)) {
+			return finishNetworkError(result.err, start, attempt)
+		}
+	}
+}
+
+// finishNetworkError records the elapsed time and attempt count on a NetworkError.
+func finishNetworkError(err error, start time.Time, attempts int) error {
+	if netErr, ok := err.(*NetworkError); ok {
+		netErr.Elapsed = time.Since(start)
+		netErr.Attempts = attempts
 	}
+	return err
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+endpoint, bytes.NewBuffer(jsonData))
+func (c *HttpClient) shouldRetry(ctx context.Context, result attemptResult) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if result.transient {
+		return c.retry.RetryNetworkErrors
+	}
+	apiErr, ok := result.err.(*APIError)
+	if !ok || apiErr.Kind == KindQuotaExceeded {
+		return false
+	}
+	return result.statusCode >= 400 && c.retry.retryableStatus(result.statusCode)
+}
+
+func (c *HttpClient) newRequest(ctx context.Context, request rawRequest) (*http.Request, error) {
+	var body io.Reader
+	if request.body != nil {
+		body = bytes.NewReader(request.body)
+	}
+	req, err := http.NewRequestWithContext(ctx, request.method, c.baseURL+request.endpoint, body)
 	if err != nil {
-		return nil, &NetworkError{Message: "failed to create HTTP request", OriginalError: err}
+		return nil, newNetworkError("failed to create HTTP request", CauseLocal, err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	for key, values := range request.header {
+		req.Header[key] = values
+	}
+	if request.body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 	req.Header.Set("Authorization", c.apiKey)
 	req.Header.Set("User-Agent", "RCK-GO-SDK/"+sdkVersion)
+	return req, nil
+}
+
+// limiterError wraps an error returned while waiting in the rate limiter.
+func limiterError(ctx context.Context, err error) *NetworkError {
+	cause := contextCause(ctx)
+	if cause == "" {
+		// The limiter gives up early when the wait would outlast the caller's deadline.
+		cause = CauseDeadline
+	}
+	return newNetworkError("rate limiter wait aborted", cause, err)
+}
+
+// transportError wraps an error returned by http.Client.Do.
+func transportError(ctx context.Context, err error) *NetworkError {
+	cause := classifyTransportError(ctx, err)
+	message := "network request failed"
+	switch cause {
+	case CauseDeadline, CauseClientTimeout:
+		message = "request timeout"
+	case CauseCanceled:
+		message = "request canceled"
+	}
+	return newNetworkError(message, cause, err)
+}
+
+func (c *HttpClient) doOnce(ctx context.Context, request rawRequest, out interface{}) attemptResult {
+	req, err := c.newRequest(ctx, request)
+	if err != nil {
+		return attemptResult{err: err}
+	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return nil, &NetworkError{Message: "request timeout"}
-		}
-		return nil, &NetworkError{Message: "network request failed", OriginalError: err}
+		netErr := transportError(ctx, err)
+		return attemptResult{err: netErr, transient: netErr.Cause.retryable()}
 	}
 	defer resp.Body.Close()
 
+	result := attemptResult{statusCode: resp.StatusCode, header: resp.Header}
+
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, &NetworkError{Message: "failed to read response body", OriginalError: err}
+		cause := classifyBodyError(ctx, err)
+		result.err = newNetworkError("failed to read response body", cause, err)
+		result.transient = cause.retryable()
+		return result
 	}
 
-	var apiResponse UnifiedAPIResponse
-	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
-		if resp.StatusCode >= 400 {
-			return nil, &APIError{
-				StatusCode: resp.StatusCode,
-				ResponseData: &UnifiedAPIResponse{
-					Error:   "Invalid response format",
-					Details: string(bodyBytes),
-				},
+	if resp.StatusCode >= 400 {
+		var apiResponse UnifiedAPIResponse
+		if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
+			apiResponse = UnifiedAPIResponse{
+				Error:   "Invalid response format",
+				Details: string(bodyBytes),
 			}
 		}
-		return nil, &NetworkError{Message: "failed to unmarshal response JSON", OriginalError: err}
+		result.err = c.handleErrorResponse(resp, bodyBytes, &apiResponse)
+		return result
 	}
 
-	if resp.StatusCode >= 400 {
-		return nil, c.handleErrorResponse(resp.StatusCode, &apiResponse)
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		result.err = newNetworkError("failed to unmarshal response JSON", CauseDecode, err)
 	}
+	return result
+}
 
-	return &apiResponse, nil
+// PostStream sends a POST request and returns the response for incremental reading.
+// Middleware run around opening the stream, so the response they see carries no output.
+// The client timeout does not apply to streams; use the context to bound them.
+// Streams are not retried. The caller must close the response body and then call release.
+func (c *HttpClient) PostStream(ctx context.Context, endpoint string, payload *UnifiedAPIRequest) (resp *http.Response, release func(), err error) {
+	open := func(ctx context.Context, call *Call) (*UnifiedAPIResponse, error) {
+		jsonData, err := json.Marshal(call.Request)
+		if err != nil {
+			return nil, newNetworkError("failed to marshal request payload", CauseLocal, err)
+		}
+		req, err := c.newRequest(ctx, rawRequest{method: http.MethodPost, endpoint: call.Endpoint, header: call.Header, body: jsonData})
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "text/event-stream, application/json")
+
+		releaseSlot, err := c.limiter.acquire(ctx, call.engine())
+		if err != nil {
+			return nil, limiterError(ctx, err)
+		}
+		streamClient := *c.httpClient
+		streamClient.Timeout = 0
+		r, err := streamClient.Do(req)
+		if err != nil {
+			releaseSlot()
+			return nil, transportError(ctx, err)
+		}
+		if r.StatusCode >= 400 {
+			defer r.Body.Close()
+			defer releaseSlot()
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return nil, newNetworkError("failed to read response body", classifyBodyError(ctx, err), err)
+			}
+			var apiResponse UnifiedAPIResponse
+			if err := json.Unmarshal(body, &apiResponse); err != nil {
+				apiResponse = UnifiedAPIResponse{Error: "Invalid response format", Details: string(body)}
+			}
+			return nil, c.handleErrorResponse(r, body, &apiResponse)
+		}
+		resp, release = r, releaseSlot
+		return &UnifiedAPIResponse{}, nil
+	}
+
+	call := newCall(ctx, endpoint, payload)
+	if _, err := chainMiddleware(open, c.middleware)(ctx, call); err != nil {
+		if resp != nil {
+			// A middleware failed after the stream was opened.
+			resp.Body.Close()
+			release()
+		}
+		return nil, nil, err
+	}
+	return resp, release, nil
 }
 
-func (c *HttpClient) handleErrorResponse(statusCode int, responseData *UnifiedAPIResponse) error {
-	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+func (c *HttpClient) handleErrorResponse(resp *http.Response, body []byte, responseData *UnifiedAPIResponse) error {
+	kind := classifyStatus(resp.StatusCode, responseData)
+	if kind == KindAuthentication {
+		// Returned as is so that err == ErrAuthentication keeps working.
 		return ErrAuthentication
 	}
-
 	return &APIError{
-		StatusCode:   statusCode,
+		StatusCode:   resp.StatusCode,
 		ResponseData: responseData,
+		Kind:         kind,
+		Header:       resp.Header,
+		RequestID:    requestID(resp.Header, body),
+		Body:         body,
+	}
+}
+
+// classifyStatus maps an error response to an ErrorKind.
+func classifyStatus(statusCode int, responseData *UnifiedAPIResponse) ErrorKind {
+	message := strings.ToLower(responseData.Error + " " + responseData.Details)
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return KindAuthentication
+	case statusCode == http.StatusPaymentRequired:
+		return KindQuotaExceeded
+	case statusCode == http.StatusTooManyRequests:
+		if strings.Contains(message, "quota") {
+			return KindQuotaExceeded
+		}
+		return KindRateLimited
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return KindInvalidProgram
+	case statusCode == http.StatusServiceUnavailable:
+		return KindEngineUnavailable
+	case statusCode >= 500:
+		if strings.Contains(message, "engine") && strings.Contains(message, "unavailable") {
+			return KindEngineUnavailable
+		}
+		return KindServer
+	default:
+		return KindAPI
+	}
+}
+
+// requestIDHeaders are the headers the API and its gateway use to report a request ID.
+var requestIDHeaders = []string{"X-Request-Id", "X-Fc-Request-Id", "X-Trace-Id"}
+
+// requestID extracts the server request ID from the response headers or a "request_id" body field.
+func requestID(header http.Header, body []byte) string {
+	for _, name := range requestIDHeaders {
+		if id := header.Get(name); id != "" {
+			return id
+		}
+	}
+	var withID struct {
+		RequestID string `json:"request_id"`
+	}
+	if json.Unmarshal(body, &withID) == nil {
+		return withID.RequestID
 	}
+	return ""
 }
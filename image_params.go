@@ -6,6 +6,8 @@ type GenerateParams struct {
 	FrameComposition string
 	Lighting         string
 	Style            string
+	// Templates renders prompts into Input, FrameComposition, Lighting or Style; see Prompt.
+	Templates map[string]*Prompt `json:"-"`
 }
 
 // Validate checks if the parameters are valid.
@@ -0,0 +1,314 @@
+package rck
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// jobsEndpoint serves POST /jobs, GET /jobs/{id} and POST /jobs/{id}/cancel. See Jobs.
+const jobsEndpoint = "/jobs"
+
+// callbackURLHeader carries JobOptions.CallbackURL on job submission.
+const callbackURLHeader = "X-RCK-Callback-URL"
+
+const (
+	defaultPollInterval    = time.Second
+	defaultMaxPollInterval = 15 * time.Second
+)
+
+// JobStatus is the lifecycle state of an asynchronous job.
+type JobStatus string
+
+const (
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// Done reports whether the job has reached a final state.
+func (s JobStatus) Done() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobCanceled
+}
+
+// ErrJobNotFinished is returned by Job.Result when the job is still queued or running.
+var ErrJobNotFinished = errors.New("rck: job has not finished")
+
+// jobState is the server's description of a job.
+type jobState struct {
+	ID       string              `json:"job_id"`
+	Status   JobStatus           `json:"status"`
+	Response *UnifiedAPIResponse `json:"response,omitempty"`
+	Error    string              `json:"error,omitempty"`
+	Details  string              `json:"details,omitempty"`
+}
+
+// Jobs runs requests as server-side jobs, for calls that may outlive the client timeout
+// such as image generation or large Auto programs. Job requests are retried and rate
+// limited like synchronous calls but do not pass through middleware.
+//
+// Experimental: the /jobs endpoints and the Idempotency-Key and X-RCK-Callback-URL
+// headers are not part of the published RCK API yet. rcktest.Server implements them;
+// check that your server does before relying on Jobs, and expect the API to change.
+type Jobs struct {
+	client *HttpClient
+
+	PollInterval    time.Duration // First delay between status polls in Wait; defaults to 1s
+	MaxPollInterval time.Duration // Upper bound of the doubling poll delay; defaults to 15s
+}
+
+// NewJobs creates a new instance of the Jobs service.
+func NewJobs(client *HttpClient) *Jobs {
+	return &Jobs{client: client}
+}
+
+// JobOptions configures a job submission.
+type JobOptions struct {
+	// CallbackURL, when set, asks the server to POST a signed completion event to this URL
+	// once the job finishes. See WebhookHandler.
+	CallbackURL string
+}
+
+// Submit starts a job for the request and returns its handle without waiting for the result.
+// Each submission carries an Idempotency-Key so retried submits do not start duplicate jobs.
+func (j *Jobs) Submit(ctx context.Context, request UnifiedAPIRequest, options ...JobOptions) (*Job, error) {
+	body, err := json.Marshal(&request)
+	if err != nil {
+		return nil, newNetworkError("failed to marshal request payload", CauseLocal, err)
+	}
+	key, err := newIdempotencyKey()
+	if err != nil {
+		return nil, newNetworkError("failed to generate idempotency key", CauseLocal, err)
+	}
+
+	header := http.Header{"Idempotency-Key": {key}}
+	if len(options) > 0 && options[0].CallbackURL != "" {
+		header.Set(callbackURLHeader, options[0].CallbackURL)
+	}
+
+	call := Call{Request: &request}
+	var state jobState
+	err = j.client.roundTrip(ctx, rawRequest{
+		method:   http.MethodPost,
+		endpoint: jobsEndpoint,
+		header:   header,
+		body:     body,
+		engine:   call.engine(),
+	}, &state)
+	if err != nil {
+		return nil, err
+	}
+	if state.ID == "" {
+		return nil, &APIError{
+			StatusCode:   http.StatusOK,
+			ResponseData: &UnifiedAPIResponse{Error: "job submission returned no job_id"},
+			Kind:         KindInvalidResponse,
+		}
+	}
+	return &Job{ID: state.ID, Engine: call.engine(), SubmittedAt: time.Now().UTC(), jobs: j, state: state}, nil
+}
+
+// Resume restores a job handle serialized with json.Marshal, possibly by another process.
+func (j *Jobs) Resume(data []byte) (*Job, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, NewValidationError("Job", fmt.Sprintf("invalid job handle: %v", err))
+	}
+	if job.ID == "" {
+		return nil, NewValidationError("Job", "job handle has no ID")
+	}
+	job.jobs = j
+	return &job, nil
+}
+
+// Job is a handle to an asynchronous job. It can be serialized with json.Marshal and
+// restored with Jobs.Resume to continue waiting from a different process.
+// Its methods are safe for concurrent use.
+type Job struct {
+	ID          string    `json:"id"`
+	Engine      Engine    `json:"engine,omitempty"`
+	SubmittedAt time.Time `json:"submitted_at"`
+
+	jobs  *Jobs
+	mu    sync.Mutex // Guards state
+	state jobState
+}
+
+// Status fetches the current status of the job.
+func (job *Job) Status(ctx context.Context) (JobStatus, error) {
+	if err := job.refresh(ctx, http.MethodGet, job.path()); err != nil {
+		return "", err
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.state.Status, nil
+}
+
+// Cancel asks the server to stop the job. Canceling a finished job has no effect.
+func (job *Job) Cancel(ctx context.Context) error {
+	return job.refresh(ctx, http.MethodPost, job.path()+"/cancel")
+}
+
+// Result returns the job's result. It returns ErrJobNotFinished while the job is still
+// queued or running, and a *JobError when the job failed or was canceled.
+func (job *Job) Result(ctx context.Context) (*JobResult, error) {
+	if _, err := job.Status(ctx); err != nil {
+		return nil, err
+	}
+	return job.result()
+}
+
+// Wait polls the job until it finishes or ctx is done, doubling the delay between polls
+// from Jobs.PollInterval up to Jobs.MaxPollInterval.
+func (job *Job) Wait(ctx context.Context) (*JobResult, error) {
+	delay := job.jobs.PollInterval
+	if delay <= 0 {
+		delay = defaultPollInterval
+	}
+	maxDelay := job.jobs.MaxPollInterval
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxPollInterval
+	}
+
+	for {
+		status, err := job.Status(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if status.Done() {
+			return job.result()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, newNetworkError("stopped waiting for job "+job.ID, contextCause(ctx), ctx.Err())
+		case <-timer.C:
+		}
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+func (job *Job) path() string {
+	return jobsEndpoint + "/" + url.PathEscape(job.ID)
+}
+
+func (job *Job) refresh(ctx context.Context, method, endpoint string) error {
+	var state jobState
+	// Status requests do not run the engine, so they skip its rate limit.
+	err := job.jobs.client.roundTrip(ctx, rawRequest{method: method, endpoint: endpoint}, &state)
+	if err != nil {
+		return err
+	}
+	if state.Status == "" {
+		return &APIError{
+			StatusCode:   http.StatusOK,
+			ResponseData: &UnifiedAPIResponse{Error: "job status missing", Details: job.ID},
+			Kind:         KindInvalidResponse,
+		}
+	}
+	state.ID = job.ID
+	job.mu.Lock()
+	job.state = state
+	job.mu.Unlock()
+	return nil
+}
+
+func (job *Job) result() (*JobResult, error) {
+	job.mu.Lock()
+	state := job.state
+	job.mu.Unlock()
+	return state.result()
+}
+
+// result converts a job state into a result or error.
+func (s *jobState) result() (*JobResult, error) {
+	switch s.Status {
+	case JobSucceeded:
+		if s.Response == nil {
+			return nil, &APIError{
+				StatusCode:   http.StatusOK,
+				ResponseData: &UnifiedAPIResponse{Error: "job succeeded without a response", Details: s.ID},
+				Kind:         KindInvalidResponse,
+			}
+		}
+		return &JobResult{Response: s.Response}, nil
+	case JobFailed, JobCanceled:
+		response := s.Response
+		if response == nil {
+			response = &UnifiedAPIResponse{Error: s.Error, Details: s.Details}
+		}
+		return nil, &JobError{JobID: s.ID, Status: s.Status, ResponseData: response}
+	default:
+		return nil, ErrJobNotFinished
+	}
+}
+
+// JobResult is the response of a finished job, decoded on demand into the type the
+// matching synchronous method returns.
+type JobResult struct {
+	Response *UnifiedAPIResponse
+}
+
+// Compute returns the result as returned by StructuredTransform, LearnFromExamples, Analyze and Translate.
+func (r *JobResult) Compute() *ComputeResponse {
+	return NewComputeResponse(*r.Response)
+}
+
+// Image returns the result as returned by Generator.Generate.
+func (r *JobResult) Image() (*ImageResponse, error) {
+	return decodeImageResponse(r.Response)
+}
+
+// Auto returns the result as returned by Kernel.Auto.
+func (r *JobResult) Auto() *AutoResult {
+	return newAutoResult(*r.Response, "")
+}
+
+// Text returns the result as returned by GenerateText.
+func (r *JobResult) Text() string {
+	return decodeText(r.Response.Output)
+}
+
+// JobError reports a job that failed or was canceled on the server.
+// A canceled job matches ErrCanceled with errors.Is.
+type JobError struct {
+	JobID        string
+	Status       JobStatus
+	ResponseData *UnifiedAPIResponse
+}
+
+func (e *JobError) Error() string {
+	if e.ResponseData != nil && e.ResponseData.Error != "" {
+		if e.ResponseData.Details != "" {
+			return fmt.Sprintf("job %s %s: %s (%s)", e.JobID, e.Status, e.ResponseData.Error, e.ResponseData.Details)
+		}
+		return fmt.Sprintf("job %s %s: %s", e.JobID, e.Status, e.ResponseData.Error)
+	}
+	return fmt.Sprintf("job %s %s", e.JobID, e.Status)
+}
+
+// Is reports whether a canceled job matches ErrCanceled.
+func (e *JobError) Is(target error) bool {
+	return e.Status == JobCanceled && target == ErrCanceled
+}
+
+func newIdempotencyKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
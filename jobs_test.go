@@ -0,0 +1,85 @@
+package rck_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func TestJobConcurrentUse(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.SetJobDuration(50 * time.Millisecond)
+
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Jobs.PollInterval = 10 * time.Millisecond
+	request := rck.UnifiedAPIRequest{
+		Program: rck.APIProgram{Input: rck.APIInput{Input: "hello"}, Pipeline: rck.APIPipeline{FunctionLogic: "echo"}},
+		Config:  &rck.APIConfig{Engine: rck.EnginePure},
+	}
+	job, err := client.Jobs.Submit(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := job.Wait(context.Background())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if result.Response == nil {
+				t.Error("finished job has no response")
+			}
+		}()
+	}
+	wg.Wait()
+
+	status, err := job.Status(context.Background())
+	if err != nil || status != rck.JobSucceeded {
+		t.Errorf("Status() = %s, %v, want %s", status, err, rck.JobSucceeded)
+	}
+}
+
+func TestJobPollsSkipEngineRateLimit(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.SetJobDuration(100 * time.Millisecond)
+
+	options := server.ClientOptions()
+	options.RateLimit = &rck.RateLimitOptions{
+		PerEngine: map[rck.Engine]rck.RateLimit{rck.EnginePure: {RequestsPerSecond: 1, Burst: 1}},
+	}
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Jobs.PollInterval = 10 * time.Millisecond
+	request := rck.UnifiedAPIRequest{
+		Program: rck.APIProgram{Input: rck.APIInput{Input: "hello"}, Pipeline: rck.APIPipeline{FunctionLogic: "echo"}},
+		Config:  &rck.APIConfig{Engine: rck.EnginePure},
+	}
+	// Submitting takes the engine's only token; polls waiting a second each for a new one would stall Wait.
+	job, err := client.Jobs.Submit(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := job.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 700*time.Millisecond {
+		t.Errorf("Wait took %s; status polls were rate limited as engine requests", elapsed)
+	}
+}
@@ -6,46 +6,70 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 const unifiedEndpoint = "/calculs"
 
 // Kernel provides access to the RCK compute functionalities.
 type Kernel struct {
-	client *HttpClient
+	client  *HttpClient
+	cache   *responseCache
+	schemas *SchemaRegistry
 }
 
 // NewKernel creates a new Kernel instance.
 func NewKernel(client *HttpClient) *Kernel {
-	return &Kernel{client: client}
+	return &Kernel{client: client, schemas: DefaultSchemas}
 }
 
 func (k *Kernel) execute(ctx context.Context, program APIProgram, config *APIConfig) (*UnifiedAPIResponse, error) {
+	return k.executeValid(ctx, program, config, nil)
+}
+
+// executeValid is like execute, but the cache neither stores nor serves responses that valid
+// rejects. A nil valid accepts every response.
+func (k *Kernel) executeValid(ctx context.Context, program APIProgram, config *APIConfig, valid func(*UnifiedAPIResponse) bool) (*UnifiedAPIResponse, error) {
 	payload := &UnifiedAPIRequest{
 		Program: program,
 		Config:  config,
 	}
-	return k.client.Post(ctx, unifiedEndpoint, payload)
+	return k.cache.do(ctx, payload, func() (*UnifiedAPIResponse, error) {
+		return k.client.Post(ctx, unifiedEndpoint, payload)
+	}, valid)
 }
 
-func outputToInterface(output json.RawMessage) (interface{}, error) {
-	var strVal string
-	if err := json.Unmarshal(output, &strVal); err == nil {
-		return strVal, nil
-	}
-	var strArrVal []string
-	if err := json.Unmarshal(output, &strArrVal); err == nil {
-		return strArrVal, nil
-	}
-	var mapVal map[string]interface{}
-	if err := json.Unmarshal(output, &mapVal); err == nil {
-		return mapVal, nil
+// SetCache installs a response cache in front of every call. Nil removes it.
+func (k *Kernel) SetCache(options *CacheOptions) {
+	k.cache = newResponseCache(options)
+}
+
+// SetSchemaRegistry sets the registry Analyze and Translate look schemas up in. Nil restores DefaultSchemas.
+func (k *Kernel) SetSchemaRegistry(registry *SchemaRegistry) {
+	if registry == nil {
+		registry = DefaultSchemas
 	}
-	return nil, errors.New("failed to determine output type")
+	k.schemas = registry
+}
+
+// CacheStats returns hit and miss counts of the response cache.
+func (k *Kernel) CacheStats() CacheStats {
+	return k.cache.stats()
 }
 
-// Auto determines the engine automatically based on parameters.
-func (k *Kernel) Auto(ctx context.Context, params AutoParams) (interface{}, error) {
+// Execute sends a raw UnifiedAPIRequest to the unified endpoint and returns the raw response.
+// It is intended for tools that store or replay requests; most callers should use the typed methods.
+func (k *Kernel) Execute(ctx context.Context, request UnifiedAPIRequest) (*UnifiedAPIResponse, error) {
+	return k.execute(ctx, request.Program, request.Config)
+}
+
+// Auto lets the server choose the engine based on the parameters, or sends the engine
+// chosen by PlanAuto when params.ClientRouting is set.
+func (k *Kernel) Auto(ctx context.Context, params AutoParams) (*AutoResult, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
@@ -85,7 +109,11 @@ func (k *Kernel) Auto(ctx context.Context, params AutoParams) (interface{}, erro
 		Pipeline: pipeline,
 	}
 
-	response, err := k.execute(ctx, program, nil) // No config, let server decide
+	var apiConfig *APIConfig // No config, let server decide
+	if params.ClientRouting {
+		apiConfig = &APIConfig{Engine: k.PlanAuto(params).Engine}
+	}
+	response, err := k.execute(ctx, program, apiConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -94,28 +122,43 @@ func (k *Kernel) Auto(ctx context.Context, params AutoParams) (interface{}, erro
 		return nil, &APIError{
 			StatusCode:   200,
 			ResponseData: response,
+			Kind:         KindInvalidResponse,
 		}
 	}
 
-	val, err := outputToInterface(response.Output)
-	if err != nil {
-		return nil, err
+	var routed Engine
+	if apiConfig != nil {
+		routed = apiConfig.Engine
 	}
+	return newAutoResult(*response, routed), nil
+}
 
-	switch v := val.(type) {
-	case string:
-		return v, nil
-	case []string:
-		return NewImageResponse(v, *response), nil
-	case map[string]interface{}:
-		return NewComputeResponse(*response), nil
+// PlanAuto predicts locally, without a request, which engine Auto routes params to:
+// attractor when Examples are set, image when FrameComposition, Lighting or Style is set,
+// standard when OutputDataClass is set, and pure otherwise. A field also counts as set
+// when params.Templates renders it.
+func (k *Kernel) PlanAuto(params AutoParams) AutoPlan {
+	isSet := func(field, value string) bool {
+		return value != "" || params.Templates[field] != nil
+	}
+	switch {
+	case len(params.Examples) > 0:
+		return AutoPlan{Engine: EngineAttractor, Reason: "Examples are set"}
+	case isSet("FrameComposition", params.FrameComposition) || isSet("Lighting", params.Lighting) || isSet("Style", params.Style):
+		return AutoPlan{Engine: EngineImage, Reason: "image parameters (FrameComposition, Lighting or Style) are set"}
+	case params.OutputDataClass != nil && params.OutputDataClass != "":
+		return AutoPlan{Engine: EngineStandard, Reason: "OutputDataClass is set"}
 	default:
-		return response.Output, nil
+		return AutoPlan{Engine: EnginePure, Reason: "no Examples, image parameters or OutputDataClass are set"}
 	}
 }
 
 // StructuredTransform performs a data transformation based on a schema and logic.
 func (k *Kernel) StructuredTransform(ctx context.Context, params StructuredTransformParams, config ...ComputeConfig) (*ComputeResponse, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
@@ -141,15 +184,76 @@ func (k *Kernel) StructuredTransform(ctx context.Context, params StructuredTrans
 		apiConfig.ComputeConfig = config[0]
 	}
 
-	response, err := k.execute(ctx, program, apiConfig)
-	if err != nil {
-		return nil, err
+	validate := params.ValidateOutput || params.MaxRepairRounds > 0
+	var valid func(*UnifiedAPIResponse) bool
+	if validate {
+		// Fail before spending a request if the schema cannot be used for validation.
+		if _, err := parseSchema(outputClassStr); err != nil {
+			return nil, err
+		}
+		valid = func(response *UnifiedAPIResponse) bool {
+			return ValidateJSON(outputClassStr, response.Output) == nil
+		}
+	}
+
+	for round := 0; ; round++ {
+		response, err := k.executeValid(ctx, program, apiConfig, valid)
+		if err != nil {
+			return nil, err
+		}
+		result := NewComputeResponse(*response)
+		result.schema = outputClassStr
+		result.repairRounds = round
+		result.templates = templateUses(ctx)
+		if !validate {
+			return result, nil
+		}
+
+		err = result.Validate()
+		if err == nil {
+			return result, nil
+		}
+		if round >= params.MaxRepairRounds {
+			return nil, err
+		}
+		var violation *SchemaViolationError
+		if !errors.As(err, &violation) {
+			return nil, err
+		}
+		program.Pipeline.CustomLogic = withRepairFeedback(params.CustomLogic, violation)
 	}
-	return NewComputeResponse(*response), nil
+}
+
+// repairFeedbackKey is the CustomLogic entry that carries corrective feedback during repair rounds.
+// A value the caller set for it is kept, followed by the feedback.
+const repairFeedbackKey = "repair_feedback"
+
+// withRepairFeedback returns a copy of customLogic with the validation errors of the previous attempt appended.
+func withRepairFeedback(customLogic map[string]string, violation *SchemaViolationError) map[string]string {
+	merged := make(map[string]string, len(customLogic)+1)
+	for k, v := range customLogic {
+		merged[k] = v
+	}
+	problems := make([]string, len(violation.Violations))
+	for i, v := range violation.Violations {
+		problems[i] = "- " + v.String()
+	}
+	feedback := fmt.Sprintf(
+		"The previous output did not conform to OutputDataClass.\nPrevious output: %s\nProblems (JSON pointer: issue):\n%s\nReturn output that satisfies OutputDataClass exactly.",
+		string(violation.Output), strings.Join(problems, "\n"))
+	if own := customLogic[repairFeedbackKey]; own != "" {
+		feedback = own + "\n\n" + feedback
+	}
+	merged[repairFeedbackKey] = feedback
+	return merged
 }
 
 // LearnFromExamples learns a transformation from input-output examples.
 func (k *Kernel) LearnFromExamples(ctx context.Context, params LearnFromExamplesParams, config ...ComputeConfig) (*ComputeResponse, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
@@ -181,11 +285,17 @@ func (k *Kernel) LearnFromExamples(ctx context.Context, params LearnFromExamples
 	if err != nil {
 		return nil, err
 	}
-	return NewComputeResponse(*response), nil
+	result := NewComputeResponse(*response)
+	result.templates = templateUses(ctx)
+	return result, nil
 }
 
 // GenerateText generates free-form text based on a prompt and logic.
 func (k *Kernel) GenerateText(ctx context.Context, params GenerateTextParams, config ...ComputeConfig) (string, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return "", err
+	}
 	if err := params.Validate(); err != nil {
 		return "", err
 	}
@@ -208,33 +318,53 @@ func (k *Kernel) GenerateText(ctx context.Context, params GenerateTextParams, co
 	if err != nil {
 		return "", err
 	}
-	var output string
-	if err := json.Unmarshal(response.Output, &output); err != nil {
-		return string(response.Output), nil
+	return decodeText(response.Output), nil
+}
+
+// decodeText returns the text of a pure-engine output, or the raw output when it is not a JSON string.
+func decodeText(output json.RawMessage) string {
+	var text string
+	if err := json.Unmarshal(output, &text); err != nil {
+		return string(output)
 	}
-	return output, nil
+	return text
 }
 
-// Analyze performs structured analysis using a predefined output format.
+// Analyze performs structured analysis using a schema of the Kernel's SchemaRegistry.
 func (k *Kernel) Analyze(ctx context.Context, params AnalyzeParams, config ...ComputeConfig) (*ComputeResponse, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
-	schema, ok := GetPredefinedSchema(params.OutputFormat)
-	if !ok {
-		return nil, NewValidationError("OutputFormat", "unknown schema name")
+	schema, err := k.schemas.text(params.OutputFormat)
+	if err != nil {
+		var validation *ValidationError
+		if errors.As(err, &validation) {
+			return nil, NewValidationError("OutputFormat", validation.Message)
+		}
+		return nil, err
 	}
 	transformParams := StructuredTransformParams{
 		Input:           params.Input,
 		FunctionLogic:   params.FunctionLogic,
 		OutputDataClass: schema,
 		CustomLogic:     params.CustomLogic,
+		ValidateOutput:  params.ValidateOutput,
+		MaxRepairRounds: params.MaxRepairRounds,
 	}
 	return k.StructuredTransform(ctx, transformParams, config...)
 }
 
-// Translate translates text to a target language.
+// Translate translates text to a target language, using the "translation" schema of the
+// Kernel's SchemaRegistry, or the predefined one if the registry has none.
 func (k *Kernel) Translate(ctx context.Context, params TranslateParams, config ...ComputeConfig) (*ComputeResponse, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
 	if err := params.Validate(); err != nil {
 		return nil, err
 	}
@@ -242,7 +372,14 @@ func (k *Kernel) Translate(ctx context.Context, params TranslateParams, config .
 	if params.IncludeCulturalNotes {
 		functionLogic += " and provide cultural background notes"
 	}
-	schema, _ := GetPredefinedSchema("translation")
+	schema, err := k.schemas.text("translation")
+	if err != nil {
+		if k.schemas.Has("translation") {
+			return nil, err
+		}
+		// Registries without a translation schema use the predefined one.
+		schema = predefinedSchemas["translation"]
+	}
 	customLogic := map[string]string{
 		"target_language":        params.TargetLanguage,
 		"include_cultural_notes": strconv.FormatBool(params.IncludeCulturalNotes),
@@ -252,6 +389,8 @@ func (k *Kernel) Translate(ctx context.Context, params TranslateParams, config .
 		FunctionLogic:   functionLogic,
 		OutputDataClass: schema,
 		CustomLogic:     customLogic,
+		ValidateOutput:  params.ValidateOutput,
+		MaxRepairRounds: params.MaxRepairRounds,
 	}
 	return k.StructuredTransform(ctx, transformParams, config...)
 }
@@ -0,0 +1,246 @@
+package rck
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit bounds the request rate and concurrency of a client or engine.
+// Zero values mean unlimited.
+type RateLimit struct {
+	RequestsPerSecond float64 // Sustained request rate
+	Burst             int     // Requests allowed at once above the sustained rate; defaults to ceil(RequestsPerSecond)
+	MaxInFlight       int     // Maximum number of concurrent requests
+}
+
+// RateLimitOptions configures the client-side limiter shared by all Kernel and Generator calls.
+// The global limit and the limit of the request's engine both apply.
+type RateLimitOptions struct {
+	RateLimit
+	PerEngine map[Engine]RateLimit // Requests without a config count as EngineAuto
+}
+
+// LimiterStats reports how much time requests spent waiting in the client-side limiter.
+type LimiterStats struct {
+	Admitted  int64         // Requests let through
+	Delayed   int64         // Admitted requests that had to wait
+	TotalWait time.Duration // Sum of waits of admitted requests
+	MaxWait   time.Duration // Longest wait of an admitted request
+	Waiting   int64         // Requests currently blocked in the limiter
+	InFlight  int64         // Requests currently holding a concurrency slot
+}
+
+// AverageWait returns the mean wait per admitted request.
+func (s LimiterStats) AverageWait() time.Duration {
+	if s.Admitted == 0 {
+		return 0
+	}
+	return s.TotalWait / time.Duration(s.Admitted)
+}
+
+type rateLimiter struct {
+	global  *limitGate
+	engines map[Engine]*limitGate
+
+	mu    sync.Mutex
+	stats LimiterStats
+}
+
+func newRateLimiter(options *RateLimitOptions) *rateLimiter {
+	if options == nil {
+		return nil
+	}
+	l := &rateLimiter{
+		global:  newLimitGate(options.RateLimit),
+		engines: make(map[Engine]*limitGate, len(options.PerEngine)),
+	}
+	for engine, limit := range options.PerEngine {
+		l.engines[engine] = newLimitGate(limit)
+	}
+	return l
+}
+
+// acquire blocks until a request for engine may be sent. The returned function releases its concurrency slots.
+func (l *rateLimiter) acquire(ctx context.Context, engine Engine) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	l.mu.Lock()
+	l.stats.Waiting++
+	l.mu.Unlock()
+
+	// Take the engine gate first so a request never holds a global slot while blocked on its engine.
+	releases := make([]func(), 0, 2)
+	acquired := make([]*limitGate, 0, 2)
+	delayed := false
+	var err error
+	for _, gate := range []*limitGate{l.engines[engine], l.global} {
+		release, waited, gateErr := gate.acquire(ctx)
+		if gateErr != nil {
+			err = gateErr
+			break
+		}
+		delayed = delayed || waited
+		releases = append(releases, release)
+		acquired = append(acquired, gate)
+	}
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+
+	wait := time.Since(start)
+	l.mu.Lock()
+	l.stats.Waiting--
+	if err == nil {
+		l.stats.Admitted++
+		l.stats.InFlight++
+		l.stats.TotalWait += wait
+		if delayed {
+			l.stats.Delayed++
+		}
+		if wait > l.stats.MaxWait {
+			l.stats.MaxWait = wait
+		}
+	}
+	l.mu.Unlock()
+
+	if err != nil {
+		releaseAll()
+		// The request is not sent, so the rate tokens taken so far are not used either.
+		for _, gate := range acquired {
+			gate.unreserve()
+		}
+		return nil, err
+	}
+	return func() {
+		releaseAll()
+		l.mu.Lock()
+		l.stats.InFlight--
+		l.mu.Unlock()
+	}, nil
+}
+
+func (l *rateLimiter) snapshot() LimiterStats {
+	if l == nil {
+		return LimiterStats{}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stats
+}
+
+// limitGate combines a token bucket and a concurrency semaphore. A nil gate admits everything.
+type limitGate struct {
+	bucket *tokenBucket
+	slots  chan struct{}
+}
+
+func newLimitGate(limit RateLimit) *limitGate {
+	if limit.RequestsPerSecond <= 0 && limit.MaxInFlight <= 0 {
+		return nil
+	}
+	gate := &limitGate{}
+	if limit.RequestsPerSecond > 0 {
+		burst := limit.Burst
+		if burst < 1 {
+			burst = int(math.Ceil(limit.RequestsPerSecond))
+		}
+		gate.bucket = &tokenBucket{rate: limit.RequestsPerSecond, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+	}
+	if limit.MaxInFlight > 0 {
+		gate.slots = make(chan struct{}, limit.MaxInFlight)
+	}
+	return gate
+}
+
+// acquire waits for the gate and reports whether it had to block.
+func (g *limitGate) acquire(ctx context.Context) (release func(), waited bool, err error) {
+	if g == nil {
+		return func() {}, false, nil
+	}
+	if g.bucket != nil {
+		if waited, err = g.bucket.wait(ctx); err != nil {
+			return nil, waited, err
+		}
+	}
+	if g.slots == nil {
+		return func() {}, waited, nil
+	}
+	select {
+	case g.slots <- struct{}{}:
+		return func() { <-g.slots }, waited, nil
+	default:
+	}
+	select {
+	case g.slots <- struct{}{}:
+		return func() { <-g.slots }, true, nil
+	case <-ctx.Done():
+		g.unreserve()
+		return nil, true, ctx.Err()
+	}
+}
+
+// unreserve returns the rate token taken by acquire for a request that is not sent.
+func (g *limitGate) unreserve() {
+	if g != nil && g.bucket != nil {
+		g.bucket.unreserve()
+	}
+}
+
+// tokenBucket is a token bucket that lets callers reserve future tokens and wait for them.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // Tokens added per second
+	burst  float64
+	tokens float64 // May go negative while callers wait for reserved tokens
+	last   time.Time
+}
+
+// reserve takes a token and returns how long the caller must wait before using it.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// unreserve returns a token taken by a caller that gave up waiting.
+func (b *tokenBucket) unreserve() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// wait blocks until a token is available and reports whether it had to block.
+func (b *tokenBucket) wait(ctx context.Context) (bool, error) {
+	delay := b.reserve()
+	if delay == 0 {
+		return false, nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		b.unreserve()
+		return true, context.DeadlineExceeded
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		b.unreserve()
+		return true, ctx.Err()
+	}
+}
@@ -0,0 +1,124 @@
+package rck_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func newLimitedClient(t *testing.T, latency time.Duration, limits *rck.RateLimitOptions) (*rck.Client, *rcktest.Server) {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	server.SetLatency(latency)
+	options := server.ClientOptions()
+	options.RateLimit = limits
+	options.Retry = &rck.RetryPolicy{MaxAttempts: 1}
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, server
+}
+
+var textParams = rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"}
+
+func TestLimiterBoundsConcurrency(t *testing.T) {
+	client, server := newLimitedClient(t, 0, &rck.RateLimitOptions{RateLimit: rck.RateLimit{MaxInFlight: 2}})
+	var inFlight, maxInFlight atomic.Int64
+	server.Handle(rck.EnginePure, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			peak := maxInFlight.Load()
+			if n <= peak || maxInFlight.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+		return rcktest.Reply{Output: "ok"}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := maxInFlight.Load(); peak != 2 {
+		t.Errorf("server saw %d concurrent requests, want 2", peak)
+	}
+	stats := client.LimiterStats()
+	if stats.Admitted != 6 || stats.Delayed == 0 || stats.InFlight != 0 || stats.Waiting != 0 {
+		t.Errorf("stats = %+v, want 6 admitted, some delayed, nothing in flight or waiting", stats)
+	}
+}
+
+func TestLimiterRateAndCanceledWaits(t *testing.T) {
+	client, _ := newLimitedClient(t, 0, &rck.RateLimitOptions{RateLimit: rck.RateLimit{RequestsPerSecond: 1, Burst: 1}})
+
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+		t.Fatal(err)
+	}
+	// The bucket is empty: a caller that cannot wait a second gives up at once.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.Compute.GenerateText(ctx, textParams); err == nil {
+		t.Fatal("request admitted above the rate limit")
+	}
+
+	stats := client.LimiterStats()
+	if stats.Admitted != 1 || stats.Waiting != 0 || stats.InFlight != 0 {
+		t.Errorf("stats = %+v, want 1 admitted and nothing waiting", stats)
+	}
+}
+
+func TestLimiterReturnsEngineTokenWhenGlobalWaitFails(t *testing.T) {
+	client, _ := newLimitedClient(t, 300*time.Millisecond, &rck.RateLimitOptions{
+		RateLimit: rck.RateLimit{MaxInFlight: 1},
+		PerEngine: map[rck.Engine]rck.RateLimit{rck.EnginePure: {RequestsPerSecond: 0.5, Burst: 1}},
+	})
+
+	// Occupy the only global slot with a request to another engine.
+	busy := make(chan struct{})
+	go func() {
+		defer close(busy)
+		_, err := client.Compute.StructuredTransform(context.Background(), rck.StructuredTransformParams{
+			Input:           "hello",
+			FunctionLogic:   "echo",
+			OutputDataClass: map[string]interface{}{"type": "object"},
+		})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// Takes the pure engine's only token, then times out waiting for the global slot.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Compute.GenerateText(ctx, textParams); err == nil {
+		t.Fatal("request admitted while the global slot was taken")
+	}
+	<-busy
+
+	// The token was returned, so this request does not wait two seconds for a new one.
+	start := time.Now()
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+		t.Fatal(err)
+	}
+	if wait := time.Since(start); wait > time.Second {
+		t.Errorf("request waited %s for a token that should have been returned", wait)
+	}
+}
@@ -0,0 +1,74 @@
+package rck
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Call describes a single API call as seen by middleware.
+// Middleware may modify Request and Header before passing the call on.
+type Call struct {
+	Endpoint string
+	Request  *UnifiedAPIRequest
+	Header   http.Header // Extra headers sent with every attempt of this call
+	// Templates lists the prompt templates that produced the request's parameters.
+	// They are also sent in the X-RCK-Prompt-Templates header.
+	Templates []TemplateUse
+}
+
+// engine returns the engine the call targets; requests without a config count as EngineAuto.
+func (c *Call) engine() Engine {
+	if c.Request.Config == nil || c.Request.Config.Engine == "" {
+		return EngineAuto
+	}
+	return c.Request.Config.Engine
+}
+
+// Invoker performs a call and returns the API response.
+type Invoker func(ctx context.Context, call *Call) (*UnifiedAPIResponse, error)
+
+// Middleware wraps an Invoker to inspect or modify calls and their responses.
+// Middleware runs once per call, outside of retries.
+type Middleware func(next Invoker) Invoker
+
+// chainMiddleware composes middleware so that the first element is the outermost.
+func chainMiddleware(final Invoker, middleware []Middleware) Invoker {
+	invoker := final
+	for i := len(middleware) - 1; i >= 0; i-- {
+		invoker = middleware[i](invoker)
+	}
+	return invoker
+}
+
+// HeaderMiddleware adds a header to every request.
+func HeaderMiddleware(key, value string) Middleware {
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call *Call) (*UnifiedAPIResponse, error) {
+			call.Header.Set(key, value)
+			return next(ctx, call)
+		}
+	}
+}
+
+// LoggingMiddleware logs the engine, duration and outcome of every call.
+// Request and response bodies are not logged.
+func LoggingMiddleware(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Invoker) Invoker {
+		return func(ctx context.Context, call *Call) (*UnifiedAPIResponse, error) {
+			engine := call.engine()
+			start := time.Now()
+			response, err := next(ctx, call)
+			if err != nil {
+				logger.Printf("rck: POST %s engine=%s duration=%s error=%v", call.Endpoint, engine, time.Since(start), err)
+			} else {
+				logger.Printf("rck: POST %s engine=%s duration=%s ok", call.Endpoint, engine, time.Since(start))
+			}
+			return response, err
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package rck_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func newMiddlewareClient(t *testing.T, server *rcktest.Server, middleware ...rck.Middleware) *rck.Client {
+	t.Helper()
+	options := server.ClientOptions()
+	options.Middleware = middleware
+	options.Retry = &rck.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+// tracing returns middleware that records when it is entered and left.
+func tracing(name string, mu *sync.Mutex, events *[]string) rck.Middleware {
+	return func(next rck.Invoker) rck.Invoker {
+		return func(ctx context.Context, call *rck.Call) (*rck.UnifiedAPIResponse, error) {
+			mu.Lock()
+			*events = append(*events, name+" in")
+			mu.Unlock()
+			response, err := next(ctx, call)
+			mu.Lock()
+			*events = append(*events, name+" out")
+			mu.Unlock()
+			return response, err
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	// Two failures are retried inside the middleware chain.
+	server.FailNext(2, http.StatusServiceUnavailable)
+
+	var mu sync.Mutex
+	var events []string
+	client := newMiddlewareClient(t, server, tracing("outer", &mu, &events), tracing("inner", &mu, &events))
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"outer in", "inner in", "inner out", "outer out"}
+	if strings.Join(events, ", ") != strings.Join(want, ", ") {
+		t.Errorf("events = %v, want %v", events, want)
+	}
+	if got := server.RequestCount(); got != 3 {
+		t.Errorf("server received %d requests, want 3", got)
+	}
+}
+
+func TestMiddlewareShortCircuits(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	var innerCalled bool
+	cached := func(next rck.Invoker) rck.Invoker {
+		return func(ctx context.Context, call *rck.Call) (*rck.UnifiedAPIResponse, error) {
+			return &rck.UnifiedAPIResponse{Output: json.RawMessage(`"from middleware"`)}, nil
+		}
+	}
+	inner := func(next rck.Invoker) rck.Invoker {
+		return func(ctx context.Context, call *rck.Call) (*rck.UnifiedAPIResponse, error) {
+			innerCalled = true
+			return next(ctx, call)
+		}
+	}
+	client := newMiddlewareClient(t, server, cached, inner)
+	text, err := client.Compute.GenerateText(context.Background(), textParams)
+	if err != nil || text != "from middleware" {
+		t.Errorf("GenerateText = %q, %v, want the middleware's response", text, err)
+	}
+	if innerCalled || server.RequestCount() != 0 {
+		t.Errorf("call went past the short-circuiting middleware")
+	}
+
+	denied := errors.New("denied")
+	client = newMiddlewareClient(t, server, func(next rck.Invoker) rck.Invoker {
+		return func(ctx context.Context, call *rck.Call) (*rck.UnifiedAPIResponse, error) {
+			return nil, denied
+		}
+	})
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); !errors.Is(err, denied) {
+		t.Errorf("error = %v, want the middleware's error", err)
+	}
+	if server.RequestCount() != 0 {
+		t.Errorf("server received %d requests, want 0", server.RequestCount())
+	}
+}
+
+func TestMiddlewareModifiesCall(t *testing.T) {
+	var header http.Header
+	var body rck.UnifiedAPIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"output": "ok"}`))
+	}))
+	defer server.Close()
+
+	rewrite := func(next rck.Invoker) rck.Invoker {
+		return func(ctx context.Context, call *rck.Call) (*rck.UnifiedAPIResponse, error) {
+			call.Request.Program.Pipeline.FunctionLogic += " politely"
+			return next(ctx, call)
+		}
+	}
+	var logs bytes.Buffer
+	client, err := rck.NewClient("test-key", &rck.ClientOptions{
+		BaseURL:    server.URL,
+		Middleware: []rck.Middleware{rck.HeaderMiddleware("X-Tenant", "acme"), rewrite, rck.LoggingMiddleware(log.New(&logs, "", 0))},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Compute.GenerateText(context.Background(), textParams); err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Tenant") != "acme" {
+		t.Errorf("X-Tenant = %q, want acme", header.Get("X-Tenant"))
+	}
+	if body.Program.Pipeline.FunctionLogic != "echo politely" {
+		t.Errorf("FunctionLogic = %q, want the rewritten logic", body.Program.Pipeline.FunctionLogic)
+	}
+	if line := logs.String(); !strings.Contains(line, "engine=pure") || !strings.Contains(line, " ok") {
+		t.Errorf("log = %q", line)
+	}
+}
@@ -0,0 +1,127 @@
+package rck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// NetworkCause pinpoints where a NetworkError happened.
+// Causes can be used directly as errors.Is targets:
+//
+//	if errors.Is(err, rck.CauseDeadline) { /* our context expired */ }
+//	if errors.Is(err, rck.CauseClientTimeout) { /* the server was too slow for ClientOptions.Timeout */ }
+type NetworkCause string
+
+// Network error causes.
+const (
+	CauseCanceled      NetworkCause = "canceled by caller"            // The caller's context was canceled
+	CauseDeadline      NetworkCause = "caller deadline exceeded"      // The caller's context deadline expired
+	CauseClientTimeout NetworkCause = "client timeout exceeded"       // ClientOptions.Timeout expired
+	CauseDNS           NetworkCause = "DNS lookup failed"             // The API host could not be resolved
+	CauseConnect       NetworkCause = "connection failed"             // The TCP connection could not be established
+	CauseTLS           NetworkCause = "TLS handshake failed"          // The TLS handshake or certificate check failed
+	CauseBodyRead      NetworkCause = "reading response body failed"  // The connection broke while reading the response
+	CauseTransport     NetworkCause = "transport error"               // Any other failure while sending the request
+	CauseLocal         NetworkCause = "request could not be prepared" // Marshaling or building the request failed
+	CauseDecode        NetworkCause = "response could not be decoded" // The response body was not valid JSON
+)
+
+func (c NetworkCause) Error() string {
+	return string(c)
+}
+
+// kind returns the coarse ErrorKind of a cause.
+func (c NetworkCause) kind() ErrorKind {
+	switch c {
+	case CauseCanceled:
+		return KindCanceled
+	case CauseDeadline, CauseClientTimeout:
+		return KindTimeout
+	case CauseLocal:
+		return ""
+	case CauseDecode:
+		return KindInvalidResponse
+	default:
+		return KindNetwork
+	}
+}
+
+// retryable reports whether a failure with this cause may succeed when retried.
+func (c NetworkCause) retryable() bool {
+	switch c {
+	case CauseClientTimeout, CauseDNS, CauseConnect, CauseBodyRead, CauseTransport:
+		return true
+	}
+	return false
+}
+
+// newNetworkError builds a NetworkError whose Kind follows from its cause.
+func newNetworkError(message string, cause NetworkCause, err error) *NetworkError {
+	return &NetworkError{Message: message, OriginalError: err, Kind: cause.kind(), Cause: cause}
+}
+
+// contextCause maps the state of the caller's context to a cause, or "" if it is still live.
+func contextCause(ctx context.Context) NetworkCause {
+	switch ctx.Err() {
+	case context.Canceled:
+		return CauseCanceled
+	case context.DeadlineExceeded:
+		return CauseDeadline
+	}
+	return ""
+}
+
+// classifyTransportError determines why http.Client.Do failed.
+func classifyTransportError(ctx context.Context, err error) NetworkCause {
+	if cause := contextCause(ctx); cause != "" {
+		return cause
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return CauseDNS
+	}
+	if isTLSError(err) {
+		return CauseTLS
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return CauseConnect
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) && urlErr.Timeout() {
+		return CauseClientTimeout
+	}
+	return CauseTransport
+}
+
+// classifyBodyError determines why reading the response body failed.
+func classifyBodyError(ctx context.Context, err error) NetworkCause {
+	if cause := contextCause(ctx); cause != "" {
+		return cause
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() || strings.Contains(err.Error(), "Client.Timeout") {
+		return CauseClientTimeout
+	}
+	return CauseBodyRead
+}
+
+func isTLSError(err error) bool {
+	var (
+		recordErr    tls.RecordHeaderError
+		alertErr     tls.AlertError
+		verifyErr    *tls.CertificateVerificationError
+		authorityErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		invalidErr   x509.CertificateInvalidError
+	)
+	return errors.As(err, &recordErr) || errors.As(err, &alertErr) || errors.As(err, &verifyErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) ||
+		strings.Contains(err.Error(), "tls: ")
+}
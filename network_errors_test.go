@@ -0,0 +1,147 @@
+package rck
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func urlError(err error) error {
+	return &url.Error{Op: "Post", URL: "https://api.example.com/unified", Err: err}
+}
+
+func TestClassifyTransportError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want NetworkCause
+	}{
+		{"DNS", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "api.example.com", IsNotFound: true}}), CauseDNS},
+		{"refused", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), CauseConnect},
+		{"dial timeout", urlError(&net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}), CauseConnect},
+		{"client timeout", urlError(timeoutError{}), CauseClientTimeout},
+		{"unknown authority", urlError(x509.UnknownAuthorityError{}), CauseTLS},
+		{"TLS alert", urlError(errors.New("remote error: tls: handshake failure")), CauseTLS},
+		{"reset", urlError(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), CauseTransport},
+		{"EOF", urlError(errors.New("EOF")), CauseTransport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyTransportError(context.Background(), tt.err); got != tt.want {
+				t.Errorf("cause = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyBodyError(t *testing.T) {
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	if got := classifyBodyError(context.Background(), reset); got != CauseBodyRead {
+		t.Errorf("reset: cause = %q, want %q", got, CauseBodyRead)
+	}
+	timeout := errors.New("net/http: request canceled (Client.Timeout or context cancellation while reading body)")
+	if got := classifyBodyError(context.Background(), timeout); got != CauseClientTimeout {
+		t.Errorf("timeout: cause = %q, want %q", got, CauseClientTimeout)
+	}
+}
+
+func TestClassifyPrefersContext(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	// Whatever the transport reports, a done context explains the failure.
+	err := urlError(timeoutError{})
+	if got := classifyTransportError(canceled, err); got != CauseCanceled {
+		t.Errorf("canceled: cause = %q", got)
+	}
+	if got := classifyTransportError(expired, err); got != CauseDeadline {
+		t.Errorf("expired: cause = %q", got)
+	}
+	if got := classifyBodyError(expired, err); got != CauseDeadline {
+		t.Errorf("expired body: cause = %q", got)
+	}
+}
+
+func TestNetworkCauseKinds(t *testing.T) {
+	tests := []struct {
+		cause     NetworkCause
+		kind      ErrorKind
+		retryable bool
+	}{
+		{CauseCanceled, KindCanceled, false},
+		{CauseDeadline, KindTimeout, false},
+		{CauseClientTimeout, KindTimeout, true},
+		{CauseDNS, KindNetwork, true},
+		{CauseConnect, KindNetwork, true},
+		{CauseTLS, KindNetwork, false},
+		{CauseBodyRead, KindNetwork, true},
+		{CauseTransport, KindNetwork, true},
+		{CauseDecode, KindInvalidResponse, false},
+	}
+	for _, tt := range tests {
+		err := newNetworkError("failed", tt.cause, nil)
+		if err.Kind != tt.kind || err.Retryable() != tt.retryable || !errors.Is(err, tt.cause) {
+			t.Errorf("%s: kind %q, retryable %v, want %q, %v", tt.cause, err.Kind, err.Retryable(), tt.kind, tt.retryable)
+		}
+	}
+}
+
+func sendOnce(t *testing.T, baseURL string) error {
+	t.Helper()
+	client, err := NewClient("test-key", &ClientOptions{BaseURL: baseURL, Retry: &RetryPolicy{MaxAttempts: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Compute.GenerateText(context.Background(), GenerateTextParams{Input: "hello", FunctionLogic: "echo"})
+	return err
+}
+
+func TestNetworkCausesFromRealConnections(t *testing.T) {
+	t.Run("refused", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr := listener.Addr().String()
+		listener.Close()
+		if err := sendOnce(t, "http://"+addr); !errors.Is(err, CauseConnect) {
+			t.Errorf("error = %v, want CauseConnect", err)
+		}
+	})
+	t.Run("untrusted certificate", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.NotFoundHandler())
+		defer server.Close()
+		if err := sendOnce(t, server.URL); !errors.Is(err, CauseTLS) {
+			t.Errorf("error = %v, want CauseTLS", err)
+		}
+	})
+	t.Run("reset while reading", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"output":`))
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}))
+		defer server.Close()
+		if err := sendOnce(t, server.URL); !errors.Is(err, CauseBodyRead) {
+			t.Errorf("error = %v, want CauseBodyRead", err)
+		}
+	})
+}
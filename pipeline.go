@@ -0,0 +1,524 @@
+package rck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrorPolicy decides what a Pipeline does when a step fails.
+type ErrorPolicy int
+
+const (
+	OnErrorAbort   ErrorPolicy = iota // Stop the pipeline and return the error
+	OnErrorSkip                       // Record the error and continue without an output for the step
+	OnErrorDefault                    // Record the error and continue with StepOptions.Default as the output
+)
+
+var errorPolicyNames = map[ErrorPolicy]string{
+	OnErrorAbort:   "abort",
+	OnErrorSkip:    "skip",
+	OnErrorDefault: "default",
+}
+
+func (p ErrorPolicy) String() string {
+	if name, ok := errorPolicyNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("ErrorPolicy(%d)", int(p))
+}
+
+// MarshalText encodes the policy as "abort", "skip" or "default".
+func (p ErrorPolicy) MarshalText() ([]byte, error) {
+	name, ok := errorPolicyNames[p]
+	if !ok {
+		return nil, fmt.Errorf("invalid error policy %d", int(p))
+	}
+	return []byte(name), nil
+}
+
+// UnmarshalText decodes "abort", "skip" or "default"; an empty string means abort.
+func (p *ErrorPolicy) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*p = OnErrorAbort
+		return nil
+	}
+	for policy, name := range errorPolicyNames {
+		if name == string(text) {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown error policy %q", text)
+}
+
+// StepStatus is the outcome of a pipeline step.
+type StepStatus string
+
+const (
+	StepSucceeded StepStatus = "succeeded"
+	StepFailed    StepStatus = "failed"
+	StepSkipped   StepStatus = "skipped"
+	StepDefaulted StepStatus = "defaulted"
+	StepCanceled  StepStatus = "canceled" // Stopped because a DAG was aborted or canceled
+)
+
+// StepOptions configures how a step gets its input and handles failure.
+//
+// The input of a step is, in order of precedence: the result of InputFunc, the
+// rendered Input template, the Input set in the step's params, and otherwise the
+// output of the previous step (the pipeline input for the first step).
+type StepOptions struct {
+	// Input is a template whose {{path}} placeholders are replaced by earlier outputs, e.g.
+	// "Summarize: {{analysis.summary}}". A path starts with a step name, "input" for the
+	// pipeline input or "prev" for the previous output, followed by .field and [index] selectors.
+	// Strings are inserted as is, other values as JSON.
+	Input     string
+	InputFunc func(run *PipelineRun) (string, error)
+	Config    *ComputeConfig // Compute configuration for the step; ignored by image steps
+	OnError   ErrorPolicy
+	Default   interface{} // Output used with OnErrorDefault
+}
+
+// StepFunc runs a custom step on its derived input. The returned value is recorded as the
+// step's Result; its JSON form is the step's Output.
+type StepFunc func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error)
+
+type pipelineStep struct {
+	name    string
+	run     StepFunc
+	options StepOptions
+}
+
+// Pipeline runs a sequence of Kernel and Generator operations, feeding each step
+// with outputs of earlier ones.
+//
+//	run, err := rck.NewPipeline(client).
+//		Translate("translation", rck.TranslateParams{TargetLanguage: "English"}).
+//		Analyze("analysis", rck.AnalyzeParams{FunctionLogic: "Summarize the key points", OutputFormat: "basic_analysis"},
+//			rck.StepOptions{Input: "{{translation.translation}}"}).
+//		GenerateText("summary", rck.GenerateTextParams{FunctionLogic: "Summarize in one sentence"},
+//			rck.StepOptions{Input: "{{translation.translation}}", OnError: rck.OnErrorSkip}).
+//		Run(ctx, text)
+type Pipeline struct {
+	client *Client
+	steps  []pipelineStep
+}
+
+// NewPipeline creates an empty pipeline running on client.
+func NewPipeline(client *Client) *Pipeline {
+	return &Pipeline{client: client}
+}
+
+// Step appends a custom step.
+func (p *Pipeline) Step(name string, run StepFunc, options ...StepOptions) *Pipeline {
+	step := pipelineStep{name: name, run: run}
+	if len(options) > 0 {
+		step.options = options[0]
+	}
+	p.steps = append(p.steps, step)
+	return p
+}
+
+// StructuredTransform appends a Kernel.StructuredTransform step.
+func (p *Pipeline) StructuredTransform(name string, params StructuredTransformParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Compute.StructuredTransform(ctx, call, config...)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// Analyze appends a Kernel.Analyze step.
+func (p *Pipeline) Analyze(name string, params AnalyzeParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Compute.Analyze(ctx, call, config...)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// Translate appends a Kernel.Translate step.
+func (p *Pipeline) Translate(name string, params TranslateParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Compute.Translate(ctx, call, config...)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// LearnFromExamples appends a Kernel.LearnFromExamples step.
+func (p *Pipeline) LearnFromExamples(name string, params LearnFromExamplesParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Compute.LearnFromExamples(ctx, call, config...)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// GenerateText appends a Kernel.GenerateText step.
+func (p *Pipeline) GenerateText(name string, params GenerateTextParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Compute.GenerateText(ctx, call, config...)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// GenerateImage appends a Generator.Generate step. Its output is the list of image data URLs.
+func (p *Pipeline) GenerateImage(name string, params GenerateParams, options ...StepOptions) *Pipeline {
+	return p.Step(name, func(ctx context.Context, input string, config ...ComputeConfig) (interface{}, error) {
+		call := params
+		call.Input = input
+		return p.client.Image.Generate(ctx, call)
+	}, withParamsInput(params.Input, options)...)
+}
+
+// withParamsInput turns an Input set in a step's params into the step's fixed input,
+// unless the options already derive one.
+func withParamsInput(input string, options []StepOptions) []StepOptions {
+	var opts StepOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if input != "" && opts.Input == "" && opts.InputFunc == nil {
+		opts.InputFunc = func(*PipelineRun) (string, error) { return input, nil }
+	}
+	return []StepOptions{opts}
+}
+
+// validate checks that step names are set, unique and not reserved.
+func (p *Pipeline) validate() error {
+	seen := make(map[string]bool, len(p.steps))
+	for i, step := range p.steps {
+		switch {
+		case step.name == "":
+			return NewValidationError("Pipeline", fmt.Sprintf("step %d has no name", i))
+		case step.name == "input" || step.name == "prev":
+			return NewValidationError("Pipeline", fmt.Sprintf("step name %q is reserved", step.name))
+		case seen[step.name]:
+			return NewValidationError("Pipeline", fmt.Sprintf("duplicate step name %q", step.name))
+		case step.run == nil:
+			return NewValidationError("Pipeline", fmt.Sprintf("step %q has no function", step.name))
+		}
+		seen[step.name] = true
+	}
+	return nil
+}
+
+// Run executes the steps in order on input. The returned run holds the trace of every step,
+// including when a step aborts the pipeline, in which case the error is a *PipelineError.
+func (p *Pipeline) Run(ctx context.Context, input string) (*PipelineRun, error) {
+	run := &PipelineRun{Input: input, outputs: map[string]interface{}{}, last: input}
+	if err := p.validate(); err != nil {
+		return run, err
+	}
+
+	for _, step := range p.steps {
+		if err := ctx.Err(); err != nil {
+			return run, &PipelineError{Step: step.name, Err: newNetworkError("pipeline canceled", contextCause(ctx), err)}
+		}
+
+		trace := StepTrace{Name: step.name}
+		start := time.Now()
+		err := run.runStep(ctx, step, &trace)
+		trace.Duration = time.Since(start)
+		if err == nil {
+			trace.Status = StepSucceeded
+			run.record(trace)
+			continue
+		}
+
+		trace.Err = err
+		switch step.options.OnError {
+		case OnErrorSkip:
+			trace.Status = StepSkipped
+			run.Trace = append(run.Trace, trace)
+		case OnErrorDefault:
+			trace.Status = StepDefaulted
+			trace.Output = step.options.Default
+			trace.Result = step.options.Default
+			run.record(trace)
+		default:
+			trace.Status = StepFailed
+			run.Trace = append(run.Trace, trace)
+			return run, &PipelineError{Step: step.name, Err: err}
+		}
+	}
+	return run, nil
+}
+
+func (r *PipelineRun) runStep(ctx context.Context, step pipelineStep, trace *StepTrace) error {
+	input, err := r.stepInput(step.options)
+	if err != nil {
+		return err
+	}
+	trace.Input = input
+
+	var config []ComputeConfig
+	if step.options.Config != nil {
+		config = append(config, *step.options.Config)
+	}
+	result, err := step.run(ctx, input, config...)
+	if err != nil {
+		return err
+	}
+	output, err := stepOutput(result)
+	if err != nil {
+		return err
+	}
+	trace.Result = result
+	trace.Output = output
+	return nil
+}
+
+func (r *PipelineRun) stepInput(options StepOptions) (string, error) {
+	switch {
+	case options.InputFunc != nil:
+		return options.InputFunc(r)
+	case options.Input != "":
+		return r.Render(options.Input)
+	default:
+		return formatValue(r.last)
+	}
+}
+
+// stepOutput converts a step result into its JSON form.
+func stepOutput(result interface{}) (interface{}, error) {
+	switch v := result.(type) {
+	case string:
+		return v, nil
+	case *ComputeResponse:
+		var output interface{}
+		if err := json.Unmarshal(v.Raw(), &output); err != nil {
+			return nil, fmt.Errorf("failed to decode step output: %w", err)
+		}
+		return output, nil
+	case *AutoResult:
+		var output interface{}
+		if err := v.Decode(&output); err != nil {
+			return nil, fmt.Errorf("failed to decode step output: %w", err)
+		}
+		return output, nil
+	case *ImageResponse:
+		urls := make([]interface{}, len(v.Images))
+		for i, image := range v.Images {
+			urls[i] = image.DataURL
+		}
+		return urls, nil
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("step result is not JSON-encodable: %w", err)
+	}
+	var output interface{}
+	err = json.Unmarshal(data, &output)
+	return output, err
+}
+
+// StepTrace records one step of a pipeline run.
+type StepTrace struct {
+	Name     string
+	Status   StepStatus
+	Input    string
+	Output   interface{} // JSON form of the result: decoded objects, strings, or image data URLs
+	Result   interface{} // Value returned by the step, e.g. *ComputeResponse, string or *ImageResponse
+	Err      error       // Error of a failed, skipped or defaulted step
+	Duration time.Duration
+}
+
+// PipelineRun is the state and trace of a pipeline execution.
+type PipelineRun struct {
+	Input string
+	Trace []StepTrace
+
+	outputs map[string]interface{}
+	last    interface{}
+}
+
+func (r *PipelineRun) record(trace StepTrace) {
+	r.Trace = append(r.Trace, trace)
+	r.outputs[trace.Name] = trace.Output
+	r.last = trace.Output
+}
+
+// Output returns the output of the last step that produced one.
+func (r *PipelineRun) Output() interface{} {
+	return r.last
+}
+
+// StepOutput returns the output of a named step, if it produced one.
+func (r *PipelineRun) StepOutput(name string) (interface{}, bool) {
+	output, ok := r.outputs[name]
+	return output, ok
+}
+
+// Step returns the trace of a named step.
+func (r *PipelineRun) Step(name string) (*StepTrace, bool) {
+	for i := range r.Trace {
+		if r.Trace[i].Name == name {
+			return &r.Trace[i], true
+		}
+	}
+	return nil, false
+}
+
+var templatePlaceholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// Render replaces the {{path}} placeholders of a template with values from the run (see StepOptions.Input).
+func (r *PipelineRun) Render(template string) (string, error) {
+	return renderTemplate(template, r.Lookup)
+}
+
+// Lookup resolves a path such as "analysis.entities[0].name" against the run's outputs.
+func (r *PipelineRun) Lookup(path string) (interface{}, error) {
+	return resolvePath(path, func(root string) (interface{}, bool) {
+		switch root {
+		case "input":
+			return r.Input, true
+		case "prev":
+			return r.last, true
+		}
+		output, ok := r.outputs[root]
+		return output, ok
+	})
+}
+
+// renderTemplate replaces each {{path}} placeholder with the formatted value lookup returns for it.
+func renderTemplate(template string, lookup func(path string) (interface{}, error)) (string, error) {
+	var renderErr error
+	rendered := templatePlaceholder.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if renderErr != nil {
+			return ""
+		}
+		path := templatePlaceholder.FindStringSubmatch(placeholder)[1]
+		value, err := lookup(path)
+		if err == nil {
+			var text string
+			if text, err = formatValue(value); err == nil {
+				return text
+			}
+		}
+		renderErr = err
+		return ""
+	})
+	return rendered, renderErr
+}
+
+// templateRoots returns the root names referenced by the placeholders of a template.
+func templateRoots(template string) ([]string, error) {
+	var roots []string
+	for _, match := range templatePlaceholder.FindAllStringSubmatch(template, -1) {
+		root, _, err := splitPath(match[1])
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// resolvePath resolves a path whose root value is looked up by name.
+func resolvePath(path string, lookupRoot func(name string) (interface{}, bool)) (interface{}, error) {
+	root, selectors, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := lookupRoot(root)
+	if !ok {
+		return nil, fmt.Errorf("path %q: no output named %q", path, root)
+	}
+
+	for _, selector := range selectors {
+		switch key := selector.(type) {
+		case string:
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q: cannot select field %q of %T", path, key, value)
+			}
+			if value, ok = object[key]; !ok {
+				return nil, fmt.Errorf("path %q: field %q not found", path, key)
+			}
+		case int:
+			array, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("path %q: cannot index %T", path, value)
+			}
+			if key < 0 || key >= len(array) {
+				return nil, fmt.Errorf("path %q: index %d out of range", path, key)
+			}
+			value = array[key]
+		}
+	}
+	return value, nil
+}
+
+// splitPath parses "name.field[0].sub" into the root name and field (string) or index (int) selectors.
+func splitPath(path string) (string, []interface{}, error) {
+	var parts []interface{}
+	rest := path
+	for rest != "" {
+		if strings.HasPrefix(rest, "[") {
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return "", nil, fmt.Errorf("path %q: unclosed [", path)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil {
+				return "", nil, fmt.Errorf("path %q: invalid index %q", path, rest[1:end])
+			}
+			parts = append(parts, index)
+			rest = strings.TrimPrefix(rest[end+1:], ".")
+			continue
+		}
+		end := strings.IndexAny(rest, ".[")
+		if end < 0 {
+			end = len(rest)
+		}
+		if end == 0 {
+			return "", nil, fmt.Errorf("path %q: empty field name", path)
+		}
+		parts = append(parts, rest[:end])
+		rest = strings.TrimPrefix(rest[end:], ".")
+	}
+
+	if len(parts) == 0 {
+		return "", nil, errors.New("empty path")
+	}
+	root, ok := parts[0].(string)
+	if !ok {
+		return "", nil, fmt.Errorf("path %q must start with a step name", path)
+	}
+	return root, parts[1:], nil
+}
+
+// formatValue renders a value for use as input: strings as is, other values as JSON.
+func formatValue(value interface{}) (string, error) {
+	if text, ok := value.(string); ok {
+		return text, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// PipelineError reports the step that aborted a pipeline.
+type PipelineError struct {
+	Step string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("pipeline step %q: %v", e.Step, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,190 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+// returns is a custom step that records its input and returns value.
+func returns(value interface{}, inputs map[string]string, name string) rck.StepFunc {
+	return func(ctx context.Context, input string, config ...rck.ComputeConfig) (interface{}, error) {
+		inputs[name] = input
+		return value, nil
+	}
+}
+
+func fails(err error) rck.StepFunc {
+	return func(ctx context.Context, input string, config ...rck.ComputeConfig) (interface{}, error) {
+		return nil, err
+	}
+}
+
+func TestPipelineChainsSteps(t *testing.T) {
+	inputs := map[string]string{}
+	run, err := rck.NewPipeline(nil).
+		Step("extract", returns(map[string]interface{}{"name": "Ada", "tags": []string{"x", "y"}}, inputs, "extract")).
+		Step("whole", returns("done", inputs, "whole")).
+		Step("picked", returns(42, inputs, "picked"), rck.StepOptions{Input: "{{extract.name}} {{extract.tags[1]}} {{input}} {{prev}}"}).
+		Step("computed", returns(nil, inputs, "computed"), rck.StepOptions{InputFunc: func(run *rck.PipelineRun) (string, error) {
+			// Outputs are in JSON form, so numbers are float64.
+			if output, _ := run.StepOutput("picked"); output != 42.0 {
+				return "", errors.New("unexpected output of picked")
+			}
+			return run.Input + "!", nil
+		}}).
+		Run(context.Background(), "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"extract":  "text",
+		"whole":    `{"name":"Ada","tags":["x","y"]}`,
+		"picked":   "Ada y text done",
+		"computed": "text!",
+	}
+	for name, input := range want {
+		if inputs[name] != input {
+			t.Errorf("input of %s = %q, want %q", name, inputs[name], input)
+		}
+	}
+	if len(run.Trace) != 4 {
+		t.Fatalf("trace has %d steps, want 4", len(run.Trace))
+	}
+	for _, trace := range run.Trace {
+		if trace.Status != rck.StepSucceeded || trace.Input != want[trace.Name] {
+			t.Errorf("trace %s = %+v", trace.Name, trace)
+		}
+	}
+	if run.Output() != nil {
+		t.Errorf("Output = %v, want the last step's nil output", run.Output())
+	}
+}
+
+func TestPipelineErrorPolicies(t *testing.T) {
+	boom := errors.New("boom")
+	inputs := map[string]string{}
+	run, err := rck.NewPipeline(nil).
+		Step("first", returns("one", inputs, "first")).
+		Step("skipped", fails(boom), rck.StepOptions{OnError: rck.OnErrorSkip}).
+		Step("afterSkip", returns("two", inputs, "afterSkip")).
+		Step("defaulted", fails(boom), rck.StepOptions{OnError: rck.OnErrorDefault, Default: "fallback"}).
+		Step("afterDefault", returns("three", inputs, "afterDefault")).
+		Run(context.Background(), "in")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A skipped step produces no output, so the next step sees the output before it.
+	if inputs["afterSkip"] != "one" || inputs["afterDefault"] != "fallback" {
+		t.Errorf("inputs = %v", inputs)
+	}
+	skipped, _ := run.Step("skipped")
+	if skipped.Status != rck.StepSkipped || skipped.Err != boom {
+		t.Errorf("skipped trace = %+v", skipped)
+	}
+	if _, ok := run.StepOutput("skipped"); ok {
+		t.Error("skipped step has an output")
+	}
+	defaulted, _ := run.Step("defaulted")
+	if output, _ := run.StepOutput("defaulted"); defaulted.Status != rck.StepDefaulted || output != "fallback" {
+		t.Errorf("defaulted trace = %+v, output %v", defaulted, output)
+	}
+}
+
+func TestPipelineAbortsOnError(t *testing.T) {
+	boom := errors.New("boom")
+	inputs := map[string]string{}
+	run, err := rck.NewPipeline(nil).
+		Step("first", returns("one", inputs, "first")).
+		Step("broken", fails(boom)).
+		Step("never", returns("two", inputs, "never")).
+		Run(context.Background(), "in")
+
+	var pipelineErr *rck.PipelineError
+	if !errors.As(err, &pipelineErr) || pipelineErr.Step != "broken" || !errors.Is(err, boom) {
+		t.Fatalf("error = %v, want a PipelineError for broken wrapping boom", err)
+	}
+	if _, ran := inputs["never"]; ran {
+		t.Error("step after the failure ran")
+	}
+	if len(run.Trace) != 2 || run.Trace[1].Status != rck.StepFailed {
+		t.Errorf("trace = %+v", run.Trace)
+	}
+
+	// A template that refers to a missing output fails the step like any other error.
+	_, err = rck.NewPipeline(nil).
+		Step("bad", returns("x", inputs, "bad"), rck.StepOptions{Input: "{{missing.field}}"}).
+		Run(context.Background(), "in")
+	if !errors.As(err, &pipelineErr) || pipelineErr.Step != "bad" {
+		t.Errorf("error = %v, want a PipelineError for bad", err)
+	}
+}
+
+func TestPipelineStopsWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	inputs := map[string]string{}
+	_, err := rck.NewPipeline(nil).
+		Step("first", func(context.Context, string, ...rck.ComputeConfig) (interface{}, error) {
+			cancel()
+			return "one", nil
+		}).
+		Step("second", returns("two", inputs, "second")).
+		Run(ctx, "in")
+	if !errors.Is(err, rck.CauseCanceled) {
+		t.Errorf("error = %v, want CauseCanceled", err)
+	}
+	if _, ran := inputs["second"]; ran {
+		t.Error("step ran after cancellation")
+	}
+}
+
+func TestPipelineRejectsInvalidSteps(t *testing.T) {
+	step := fails(errors.New("unused"))
+	pipelines := map[string]*rck.Pipeline{
+		"unnamed":   rck.NewPipeline(nil).Step("", step),
+		"reserved":  rck.NewPipeline(nil).Step("prev", step),
+		"duplicate": rck.NewPipeline(nil).Step("a", step).Step("a", step),
+		"no func":   rck.NewPipeline(nil).Step("a", nil),
+	}
+	for name, pipeline := range pipelines {
+		var validation *rck.ValidationError
+		if _, err := pipeline.Run(context.Background(), "in"); !errors.As(err, &validation) {
+			t.Errorf("%s: error = %v, want a ValidationError", name, err)
+		}
+	}
+}
+
+func TestPipelineKernelSteps(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := map[string]interface{}{"type": "object", "properties": map[string]interface{}{"topic": map[string]interface{}{"type": "string"}}, "required": []string{"topic"}}
+	run, err := rck.NewPipeline(client).
+		StructuredTransform("extract", rck.StructuredTransformParams{FunctionLogic: "Find the topic", OutputDataClass: schema}).
+		GenerateText("write", rck.GenerateTextParams{FunctionLogic: "Write about it"}, rck.StepOptions{Input: "Topic: {{extract.topic}}"}).
+		Run(context.Background(), "some text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := server.Requests()
+	if len(requests) != 2 || requests[0].Program.Input.Input != "some text" {
+		t.Fatalf("requests = %+v", requests)
+	}
+	topic, err := run.Lookup("extract.topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := requests[1].Program.Input.Input; got != "Topic: "+topic.(string) {
+		t.Errorf("second step input = %q, want the rendered topic %q", got, topic)
+	}
+	if _, ok := run.Output().(string); !ok {
+		t.Errorf("Output = %#v, want the generated text", run.Output())
+	}
+}
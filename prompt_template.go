@@ -0,0 +1,308 @@
+package rck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"unicode"
+)
+
+// PromptTemplate is a named, versioned text/template used to build FunctionLogic,
+// CustomLogic or any other string parameter:
+//
+//	tmpl, err := rck.ParsePromptTemplate("first-order", "v1", "新客户首单享受{{.discount}}折")
+//	params := rck.StructuredTransformParams{
+//		Input:           order,
+//		OutputDataClass: schema,
+//		Templates:       map[string]*rck.Prompt{"FunctionLogic": tmpl.With(map[string]interface{}{"discount": 8})},
+//	}
+//
+// Rendering fails when the template refers to a variable that is not set. String
+// variables are escaped with EscapePromptInput unless they are of type Trusted.
+type PromptTemplate struct {
+	Name    string
+	Version string
+	Text    string
+
+	tmpl *template.Template
+}
+
+// Trusted marks a template variable that is inserted without escaping.
+type Trusted string
+
+var promptFuncs = template.FuncMap{
+	// quote inserts a value as a JSON string literal.
+	"quote": func(v interface{}) (string, error) {
+		data, err := json.Marshal(fmt.Sprint(v))
+		return string(data), err
+	},
+	"join": func(items []interface{}, sep string) string {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"trim": strings.TrimSpace,
+}
+
+// ParsePromptTemplate parses a standalone template. Use a PromptRegistry to share partials.
+func ParsePromptTemplate(name, version, text string) (*PromptTemplate, error) {
+	return parsePromptTemplate(name, version, text, nil)
+}
+
+func parsePromptTemplate(name, version, text string, partials map[string]string) (*PromptTemplate, error) {
+	if name == "" {
+		return nil, NewValidationError("Name", "is required")
+	}
+	root := template.New(name).Option("missingkey=error").Funcs(promptFuncs)
+	names := make([]string, 0, len(partials))
+	for partial := range partials {
+		names = append(names, partial)
+	}
+	sort.Strings(names)
+	for _, partial := range names {
+		if _, err := root.New(partial).Parse(partials[partial]); err != nil {
+			return nil, NewValidationError("Template", fmt.Sprintf("partial %q: %v", partial, err))
+		}
+	}
+	if _, err := root.Parse(text); err != nil {
+		return nil, NewValidationError("Template", fmt.Sprintf("%s: %v", promptRef(name, version), err))
+	}
+	return &PromptTemplate{Name: name, Version: version, Text: text, tmpl: root}, nil
+}
+
+// Ref returns "name@version", or the name alone for an unversioned template.
+func (t *PromptTemplate) Ref() string {
+	return promptRef(t.Name, t.Version)
+}
+
+func promptRef(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
+
+// Render executes the template with vars.
+func (t *PromptTemplate) Render(vars map[string]interface{}) (string, error) {
+	if t.tmpl == nil {
+		return "", NewValidationError("Template", fmt.Sprintf("%s was not parsed", t.Ref()))
+	}
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, escapeVars(vars)); err != nil {
+		return "", NewValidationError("Template", fmt.Sprintf("%s: %v", t.Ref(), err))
+	}
+	return out.String(), nil
+}
+
+// With binds variables to the template for use in a params Templates map.
+func (t *PromptTemplate) With(vars map[string]interface{}) *Prompt {
+	return &Prompt{Template: t, Vars: vars}
+}
+
+// EscapePromptInput makes user-provided text safe to insert into a prompt by removing
+// control characters other than newlines and tabs. Other text, such as JSON or code,
+// is kept as is: variables are inserted into the rendered output, never parsed as a template.
+func EscapePromptInput(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// escapeVars escapes strings inside vars, including in nested maps and slices.
+func escapeVars(vars map[string]interface{}) map[string]interface{} {
+	escaped := make(map[string]interface{}, len(vars))
+	for key, value := range vars {
+		escaped[key] = escapeValue(value)
+	}
+	return escaped
+}
+
+func escapeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case Trusted:
+		return string(v)
+	case string:
+		return EscapePromptInput(v)
+	case []string:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = EscapePromptInput(item)
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = escapeValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		return escapeVars(v)
+	case map[string]string:
+		items := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			items[key] = EscapePromptInput(item)
+		}
+		return items
+	default:
+		return value
+	}
+}
+
+// Prompt is a template with its variables, accepted in place of a literal string parameter.
+// In the Templates map of a params struct, the key names the parameter the rendered text
+// replaces: a string field of that struct, such as "FunctionLogic", or an entry of its
+// CustomLogic map, such as "CustomLogic.rules".
+type Prompt struct {
+	Template *PromptTemplate
+	Vars     map[string]interface{}
+}
+
+// TemplateUse records that a template produced a request parameter.
+type TemplateUse struct {
+	Field   string // Parameter name, e.g. "FunctionLogic" or "CustomLogic.rules"
+	Name    string
+	Version string
+}
+
+func (u TemplateUse) String() string {
+	return u.Field + "=" + promptRef(u.Name, u.Version)
+}
+
+// templateUsesKey carries the TemplateUses of the request being built.
+type templateUsesKey struct{}
+
+// templateUses returns the templates recorded on ctx.
+func templateUses(ctx context.Context) []TemplateUse {
+	uses, _ := ctx.Value(templateUsesKey{}).([]TemplateUse)
+	return uses
+}
+
+// applyPrompts renders the prompts of a params struct into its fields and records them on ctx.
+// Keys name a string field ("FunctionLogic") or a CustomLogic entry ("CustomLogic.rules").
+func applyPrompts(ctx context.Context, params interface{}, prompts map[string]*Prompt) (context.Context, error) {
+	if len(prompts) == 0 {
+		return ctx, nil
+	}
+	fields := make([]string, 0, len(prompts))
+	for field := range prompts {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	target := reflect.ValueOf(params).Elem()
+	uses := append([]TemplateUse(nil), templateUses(ctx)...)
+	copied := false
+	for _, field := range fields {
+		prompt := prompts[field]
+		if prompt == nil || prompt.Template == nil {
+			return ctx, NewValidationError("Templates", fmt.Sprintf("%s has no template", field))
+		}
+		text, err := prompt.Template.Render(prompt.Vars)
+		if err != nil {
+			return ctx, err
+		}
+
+		if key, ok := strings.CutPrefix(field, "CustomLogic."); ok {
+			customLogic := target.FieldByName("CustomLogic")
+			if !customLogic.IsValid() || customLogic.Type() != reflect.TypeOf(map[string]string(nil)) {
+				return ctx, NewValidationError("Templates", fmt.Sprintf("%s: params have no CustomLogic", field))
+			}
+			if !copied {
+				// Copy so rendering never mutates the caller's map.
+				logic := make(map[string]string, customLogic.Len()+1)
+				for iter := customLogic.MapRange(); iter.Next(); {
+					logic[iter.Key().String()] = iter.Value().String()
+				}
+				customLogic.Set(reflect.ValueOf(logic))
+				copied = true
+			}
+			customLogic.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(text))
+		} else {
+			value := target.FieldByName(field)
+			if !value.IsValid() || value.Kind() != reflect.String || !value.CanSet() {
+				return ctx, NewValidationError("Templates", fmt.Sprintf("%s is not a string parameter", field))
+			}
+			value.SetString(text)
+		}
+		uses = append(uses, TemplateUse{Field: field, Name: prompt.Template.Name, Version: prompt.Template.Version})
+	}
+	return context.WithValue(ctx, templateUsesKey{}, uses), nil
+}
+
+// PromptRegistry holds prompt templates by name and version, and partials they can include
+// with {{template "partial" .}}. It is safe for concurrent use.
+type PromptRegistry struct {
+	mu        sync.RWMutex
+	partials  map[string]string
+	templates versioned[*PromptTemplate]
+}
+
+// NewPromptRegistry creates an empty registry.
+func NewPromptRegistry() *PromptRegistry {
+	return &PromptRegistry{
+		partials:  map[string]string{},
+		templates: newVersioned("template", func(t *PromptTemplate) string { return t.Version }),
+	}
+}
+
+// Partial defines a reusable partial. Partials are available to templates registered afterwards.
+func (r *PromptRegistry) Partial(name, text string) error {
+	if _, err := template.New(name).Funcs(promptFuncs).Parse(text); err != nil {
+		return NewValidationError("Template", fmt.Sprintf("partial %q: %v", name, err))
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.partials[name] = text
+	return nil
+}
+
+// Register parses and stores a template. A name and version can be registered only once.
+func (r *PromptRegistry) Register(name, version, text string) (*PromptTemplate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	tmpl, err := parsePromptTemplate(name, version, text, r.partials)
+	if err != nil {
+		return nil, err
+	}
+	if !r.templates.add(name, tmpl) {
+		return nil, NewValidationError("Template", fmt.Sprintf("%s is already registered", promptRef(name, version)))
+	}
+	return tmpl, nil
+}
+
+// Get returns the template for "name@version", or the latest version for a bare name.
+func (r *PromptRegistry) Get(ref string) (*PromptTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tmpl, err := r.templates.get(ref)
+	if err != nil {
+		return nil, NewValidationError("Template", err.Error())
+	}
+	return tmpl, nil
+}
+
+// Prompt looks up a template like Get and binds vars to it.
+func (r *PromptRegistry) Prompt(ref string, vars map[string]interface{}) (*Prompt, error) {
+	tmpl, err := r.Get(ref)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.With(vars), nil
+}
+
+// Names returns the names of the registered templates in sorted order.
+func (r *PromptRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.templates.names()
+}
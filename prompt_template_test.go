@@ -0,0 +1,41 @@
+package rck_test
+
+import (
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+func TestEscapePromptInput(t *testing.T) {
+	tests := map[string]string{
+		"line\none\ttab":            "line\none\ttab",
+		"bell\a and \x1b[31mred":    "bell and [31mred",
+		`{"a": {"b": {"c": 1}}}`:    `{"a": {"b": {"c": 1}}}`,
+		"func f() { if x {{}} }":    "func f() { if x {{}} }",
+		"{{.Secret}} stays literal": "{{.Secret}} stays literal",
+	}
+	for in, want := range tests {
+		if got := rck.EscapePromptInput(in); got != want {
+			t.Errorf("EscapePromptInput(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestPromptTemplateEscapesVariables(t *testing.T) {
+	tmpl, err := rck.ParsePromptTemplate("extract", "v1", "Data: {{.data}} Rules: {{.rules}} Notes: {{index .notes 0}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := tmpl.Render(map[string]interface{}{
+		"data":  `{"order": {"items": [{"sku": "a"}]}}` + "\x00",
+		"rules": rck.Trusted("keep\x00 this"),
+		"notes": []string{"first\x07"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `Data: {"order": {"items": [{"sku": "a"}]}} Rules: keep` + "\x00" + ` this Notes: first`
+	if text != want {
+		t.Errorf("Render = %q, want %q", text, want)
+	}
+}
@@ -0,0 +1,318 @@
+package rcktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Mode selects how a Recorder treats requests.
+type Mode int
+
+const (
+	// ModeReplay serves every request from the cassette and fails on unmatched requests.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the real transport and overwrites the cassette,
+	// which is emptied when the Recorder is created.
+	ModeRecord
+	// ModeRecordMissing replays matching requests and records the others.
+	ModeRecordMissing
+	// ModePassthrough sends every request to the real transport without recording.
+	ModePassthrough
+)
+
+var modeNames = map[string]Mode{
+	"replay":         ModeReplay,
+	"record":         ModeRecord,
+	"record-missing": ModeRecordMissing,
+	"passthrough":    ModePassthrough,
+}
+
+// ParseMode parses "replay", "record", "record-missing" or "passthrough".
+func ParseMode(s string) (Mode, error) {
+	mode, ok := modeNames[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("rcktest: unknown cassette mode %q", s)
+	}
+	return mode, nil
+}
+
+// ModeFromEnv reads the cassette mode from an environment variable, returning fallback when it is unset.
+func ModeFromEnv(name string, fallback Mode) (Mode, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return ParseMode(value)
+}
+
+// scrubbedHeaders are never written to a cassette.
+var scrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// Interaction is a recorded request/response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the stored form of an HTTP request.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	Path   string      `json:"path"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// RecordedResponse is the stored form of an HTTP response.
+type RecordedResponse struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+type cassetteFile struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// UnmatchedRequestError is returned in replay mode when no recorded interaction matches a request.
+type UnmatchedRequestError struct {
+	Cassette string
+	Method   string
+	Path     string
+	Body     string // Normalized request body
+}
+
+func (e *UnmatchedRequestError) Error() string {
+	return fmt.Sprintf("rcktest: no interaction in cassette %s matches %s %s with body %s", e.Cassette, e.Method, e.Path, e.Body)
+}
+
+// Recorder is an http.RoundTripper that records traffic to, or replays it from, a cassette file.
+// Install it with rck.ClientOptions.Transport:
+//
+//	rec, err := rcktest.NewRecorder("testdata/extract.json", rcktest.ModeReplay, nil)
+//	client, err := rck.NewClient(apiKey, &rck.ClientOptions{Transport: rec})
+//
+// Requests are matched on method, path and the request body compared as normalized
+// JSON, so key order and whitespace do not matter. Identical requests recorded
+// several times are replayed in recording order.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewRecorder creates a Recorder for the cassette at path.
+// next is the transport used for real requests; nil means http.DefaultTransport.
+func NewRecorder(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	r := &Recorder{path: path, mode: mode, next: next}
+
+	if mode == ModeReplay || mode == ModeRecordMissing {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var file cassetteFile
+			if err := json.Unmarshal(data, &file); err != nil {
+				return nil, fmt.Errorf("rcktest: invalid cassette %s: %w", path, err)
+			}
+			r.interactions = file.Interactions
+			r.used = make([]bool, len(file.Interactions))
+		case os.IsNotExist(err) && mode == ModeRecordMissing:
+		default:
+			return nil, fmt.Errorf("rcktest: cannot load cassette: %w", err)
+		}
+	}
+	if mode == ModeRecord {
+		// Start afresh so no interaction of an earlier recording survives.
+		if err := r.save(); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Interactions returns a copy of the interactions currently held by the recorder.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModePassthrough {
+		return r.next.RoundTrip(req)
+	}
+
+	body, req, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	normalized := normalizeJSON(body)
+
+	if r.mode == ModeReplay || r.mode == ModeRecordMissing {
+		if interaction, ok := r.match(req.Method, req.URL.Path, normalized); ok {
+			return interaction.Response.toHTTP(req), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, &UnmatchedRequestError{Cassette: r.path, Method: req.Method, Path: req.URL.Path, Body: normalized}
+		}
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: req.Method,
+			Path:   req.URL.Path,
+			Header: scrub(req.Header),
+			Body:   normalized,
+		},
+		Response: RecordedResponse{
+			Status: resp.StatusCode,
+			Header: scrub(resp.Header),
+			Body:   string(respBody),
+		},
+	}
+	if err := r.record(interaction); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) match(method, path, body string) (Interaction, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i, interaction := range r.interactions {
+		recorded := interaction.Request
+		if recorded.Method != method || recorded.Path != path || normalizeJSON([]byte(recorded.Body)) != body {
+			continue
+		}
+		if !r.used[i] {
+			r.used[i] = true
+			return interaction, true
+		}
+		last = i
+	}
+	if last >= 0 {
+		return r.interactions[last], true
+	}
+	return Interaction{}, false
+}
+
+// record appends an interaction and rewrites the cassette so partial runs are kept.
+func (r *Recorder) record(interaction Interaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.interactions = append(r.interactions, interaction)
+	r.used = append(r.used, true)
+	return r.save()
+}
+
+// save writes the interactions to the cassette. The caller must hold r.mu or own r exclusively.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(cassetteFile{Interactions: r.interactions}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("rcktest: failed to encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("rcktest: failed to create cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0644); err != nil {
+		return fmt.Errorf("rcktest: failed to write cassette: %w", err)
+	}
+	return nil
+}
+
+func (rr RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	header := rr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.Status, http.StatusText(rr.Status)),
+		StatusCode:    rr.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
+
+// readRequestBody returns the body of req and the request to send on. RoundTrippers must not
+// modify req, so the body is read from GetBody when possible, or else from req into a clone.
+func readRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer rc.Close()
+		body, err := io.ReadAll(rc)
+		return body, req, err
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, clone, nil
+}
+
+// normalizeJSON re-encodes a JSON body with sorted keys and no insignificant whitespace.
+// Bodies that are not JSON are returned unchanged.
+func normalizeJSON(body []byte) string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return string(body)
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return string(body)
+	}
+	return string(normalized)
+}
+
+func scrub(header http.Header) http.Header {
+	clean := header.Clone()
+	for _, name := range scrubbedHeaders {
+		clean.Del(name)
+	}
+	return clean
+}
@@ -0,0 +1,91 @@
+package rcktest_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func TestRecordThenReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	server := rcktest.NewServer()
+	baseURL := server.ClientOptions().BaseURL
+
+	generate := func(mode rcktest.Mode) (string, error) {
+		rec, err := rcktest.NewRecorder(path, mode, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client, err := rck.NewClient("test-key", &rck.ClientOptions{BaseURL: baseURL, Transport: rec, Retry: &rck.RetryPolicy{MaxAttempts: 1}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return client.Compute.GenerateText(context.Background(), rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"})
+	}
+
+	recorded, err := generate(rcktest.ModeRecord)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if requests := server.Requests(); len(requests) != 1 || requests[0].Program.Input.Input != "hello" {
+		t.Fatalf("server received %+v, want the recorded request", requests)
+	}
+	server.Close()
+
+	replayed, err := generate(rcktest.ModeReplay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed %q, recorded %q", replayed, recorded)
+	}
+}
+
+func TestRecordTruncatesCassette(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(`{"interactions": [{"request": {"method": "POST", "path": "/old", "body": ""}, "response": {"status": 200, "body": ""}}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rcktest.NewRecorder(path, rcktest.ModeRecord, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := rcktest.NewRecorder(path, rcktest.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interactions := rec.Interactions(); len(interactions) != 0 {
+		t.Errorf("cassette still holds %d interactions after recording nothing", len(interactions))
+	}
+}
+
+func TestRecorderDoesNotModifyRequest(t *testing.T) {
+	rec, err := rcktest.NewRecorder(filepath.Join(t.TempDir(), "cassette.json"), rcktest.ModeRecordMissing, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := rcktest.NewServer()
+	defer server.Close()
+
+	body := io.NopCloser(strings.NewReader(`{"program": {"input": {"input": "hello"}}}`))
+	req, err := http.NewRequest(http.MethodPost, server.ClientOptions().BaseURL+"/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.GetBody = nil
+	resp, err := rec.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if req.Body != body {
+		t.Error("RoundTrip replaced the request body")
+	}
+}
@@ -0,0 +1,201 @@
+package rcktest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+// fakeJob is a job accepted on JobsEndpoint. Its reply is computed at submission
+// and revealed once the job duration has elapsed.
+type fakeJob struct {
+	id          string
+	reply       Reply
+	readyAt     time.Time
+	canceled    bool
+	callbackURL string
+	delivered   bool
+}
+
+// jobStatus mirrors the job description the SDK expects.
+type jobStatus struct {
+	ID       string                  `json:"job_id"`
+	Status   rck.JobStatus           `json:"status"`
+	Response *rck.UnifiedAPIResponse `json:"response,omitempty"`
+	Error    string                  `json:"error,omitempty"`
+	Details  string                  `json:"details,omitempty"`
+}
+
+// SetJobDuration makes submitted jobs report running for d before they finish.
+func (s *Server) SetJobDuration(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobDuration = d
+}
+
+// SetWebhookSecret sets the secret used to sign completion callbacks of jobs
+// submitted with a callback URL. Without a secret, callbacks are sent unsigned.
+func (s *Server) SetWebhookSecret(secret string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhookSecret = secret
+}
+
+// JobCount returns the number of distinct jobs submitted so far.
+func (s *Server) JobCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.jobs)
+}
+
+func (s *Server) serveSubmitJob(w http.ResponseWriter, r *http.Request) {
+	req, ok := s.readRequest(w, r)
+	if !ok || !s.admit(w, r) {
+		return
+	}
+
+	key := r.Header.Get("Idempotency-Key")
+	s.mu.Lock()
+	if id, ok := s.jobKeys[key]; ok && key != "" {
+		job := s.jobs[id]
+		s.mu.Unlock()
+		writeJSON(w, http.StatusAccepted, job.status(time.Now()))
+		return
+	}
+	job := &fakeJob{
+		id:          fmt.Sprintf("job-%d", len(s.jobs)+1),
+		readyAt:     time.Now().Add(s.jobDuration),
+		callbackURL: r.Header.Get("X-RCK-Callback-URL"),
+	}
+	s.jobs[job.id] = job
+	if key != "" {
+		s.jobKeys[key] = job.id
+	}
+	s.mu.Unlock()
+
+	reply := s.handlerFor(engineOf(req))(req)
+	s.mu.Lock()
+	job.reply = reply
+	status := job.status(time.Now())
+	duration := job.readyAt.Sub(time.Now())
+	s.mu.Unlock()
+	if job.callbackURL != "" {
+		time.AfterFunc(duration, func() { s.deliver(job) })
+	}
+	writeJSON(w, http.StatusAccepted, status)
+}
+
+func (s *Server) serveJobStatus(w http.ResponseWriter, r *http.Request) {
+	s.serveJob(w, r, false)
+}
+
+func (s *Server) serveCancelJob(w http.ResponseWriter, r *http.Request) {
+	s.serveJob(w, r, true)
+}
+
+func (s *Server) serveJob(w http.ResponseWriter, r *http.Request, cancel bool) {
+	if !s.admit(w, r) {
+		return
+	}
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	if !ok {
+		s.mu.Unlock()
+		writeReply(w, Reply{Status: http.StatusNotFound, Error: "job not found", Details: r.PathValue("id")})
+		return
+	}
+	now := time.Now()
+	canceled := cancel && !job.status(now).Status.Done()
+	if canceled {
+		job.canceled = true
+	}
+	status := job.status(now)
+	s.mu.Unlock()
+	if canceled && job.callbackURL != "" {
+		go s.deliver(job)
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// deliver posts the job's signed completion event to its callback URL, once.
+func (s *Server) deliver(job *fakeJob) {
+	s.mu.Lock()
+	if job.delivered {
+		s.mu.Unlock()
+		return
+	}
+	job.delivered = true
+	status := job.status(time.Now())
+	secret := s.webhookSecret
+	s.mu.Unlock()
+
+	body, _ := json.Marshal(status)
+	req, err := http.NewRequest(http.MethodPost, job.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(rck.WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	if secret != "" {
+		req.Header.Set(rck.WebhookSignatureHeader, rck.SignWebhook(secret, now, body))
+	}
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+}
+
+// status describes the job at the given time. The caller must hold the server lock.
+func (j *fakeJob) status(now time.Time) jobStatus {
+	status := jobStatus{ID: j.id}
+	switch {
+	case j.canceled:
+		status.Status = rck.JobCanceled
+	case now.Before(j.readyAt):
+		status.Status = rck.JobRunning
+	default:
+		response := j.reply.response()
+		if j.reply.Status >= 400 {
+			status.Status = rck.JobFailed
+			status.Error = response.Error
+			status.Details = response.Details
+		} else {
+			status.Status = rck.JobSucceeded
+			status.Response = response
+		}
+	}
+	return status
+}
+
+// response returns the UnifiedAPIResponse a reply stands for.
+func (r Reply) response() *rck.UnifiedAPIResponse {
+	response := &rck.UnifiedAPIResponse{Error: r.Error, Details: r.Details, Engine: r.Engine}
+	switch {
+	case r.Body != nil:
+		if err := json.Unmarshal(r.Body, response); err != nil {
+			return &rck.UnifiedAPIResponse{Error: "Invalid response format", Details: string(r.Body)}
+		}
+	case r.Stream != nil:
+		response.Output, _ = json.Marshal(strings.Join(r.Stream, ""))
+	case r.Output != nil:
+		output, err := json.Marshal(r.Output)
+		if err != nil {
+			return &rck.UnifiedAPIResponse{Error: "rcktest: failed to marshal output", Details: err.Error()}
+		}
+		response.Output = output
+	}
+	return response
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	body, _ := json.Marshal(v)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
@@ -0,0 +1,402 @@
+// Package rcktest provides an in-process fake RCK server for offline testing.
+//
+// The server speaks the UnifiedAPIRequest/UnifiedAPIResponse protocol on the
+// unified endpoint, answers every engine with plausible output by default, and
+// lets tests register their own handlers and inject latency or failures:
+//
+//	srv := rcktest.NewServer()
+//	defer srv.Close()
+//	srv.FailNext(2, http.StatusServiceUnavailable)
+//	client, _ := rck.NewClient("test-key", srv.ClientOptions())
+package rcktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+// UnifiedEndpoint is the path the SDK posts programs to.
+const UnifiedEndpoint = "/calculs"
+
+// JobsEndpoint is the path the SDK submits asynchronous jobs to.
+// Job status is served at JobsEndpoint/{id} and cancellation at JobsEndpoint/{id}/cancel.
+const JobsEndpoint = "/jobs"
+
+// Reply is what a Handler returns for a request.
+type Reply struct {
+	Status  int         // HTTP status; defaults to 200
+	Header  http.Header // Extra response headers
+	Output  interface{} // Marshaled into UnifiedAPIResponse.Output
+	Error   string
+	Details string
+	Engine  rck.Engine // Reported as the engine that ran the program; set by the auto handler
+	Body    []byte     // Raw response body; when set, Output, Error, Details and Engine are ignored
+
+	// Stream, when set, is sent as server-sent events: one {"delta": ...} frame per
+	// element, then a frame with the joined output and a final [DONE] frame.
+	Stream      []string
+	StreamDelay time.Duration // Pause between stream frames
+}
+
+// Handler produces the reply for a request routed to an engine.
+type Handler func(req *rck.UnifiedAPIRequest) Reply
+
+// Fault describes a failure injected in place of a normal reply.
+type Fault struct {
+	Status    int           // HTTP status to return; defaults to 500
+	Error     string        // Error message in the response body
+	Malformed bool          // Return a body that is not valid JSON
+	Latency   time.Duration // Delay before replying
+	Header    http.Header   // Extra response headers, e.g. Retry-After
+}
+
+// Server is a fake RCK server backed by httptest.Server.
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[rck.Engine]Handler
+	faults   []Fault
+	latency  time.Duration
+	apiKey   string
+	requests []rck.UnifiedAPIRequest
+
+	jobs          map[string]*fakeJob
+	jobKeys       map[string]string // Idempotency-Key to job ID
+	jobDuration   time.Duration
+	webhookSecret string
+}
+
+// NewServer starts a fake server with default handlers for every engine.
+func NewServer() *Server {
+	s := &Server{handlers: map[rck.Engine]Handler{}, jobs: map[string]*fakeJob{}, jobKeys: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc(UnifiedEndpoint, s.serveUnified)
+	mux.HandleFunc("POST "+JobsEndpoint, s.serveSubmitJob)
+	mux.HandleFunc("GET "+JobsEndpoint+"/{id}", s.serveJobStatus)
+	mux.HandleFunc("POST "+JobsEndpoint+"/{id}/cancel", s.serveCancelJob)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// ClientOptions returns options that point an rck.Client at this server.
+func (s *Server) ClientOptions() *rck.ClientOptions {
+	return &rck.ClientOptions{BaseURL: s.URL}
+}
+
+// Handle registers the handler for an engine, replacing the default one.
+// Requests without a config are routed to rck.EngineAuto, which hands them to the handler
+// of the engine it picks unless an rck.EngineAuto handler is registered.
+func (s *Server) Handle(engine rck.Engine, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[engine] = handler
+}
+
+// RequireAPIKey makes the server reject requests whose Authorization header differs from key.
+func (s *Server) RequireAPIKey(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apiKey = key
+}
+
+// SetLatency delays every reply by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// InjectFault queues faults; each subsequent request consumes one before normal handling resumes.
+func (s *Server) InjectFault(faults ...Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// FailNext makes the next n requests fail with the given HTTP status.
+func (s *Server) FailNext(n int, status int) {
+	for i := 0; i < n; i++ {
+		s.InjectFault(Fault{Status: status})
+	}
+}
+
+// MalformNext makes the next n requests return a successful status with a body that is not JSON.
+func (s *Server) MalformNext(n int) {
+	for i := 0; i < n; i++ {
+		s.InjectFault(Fault{Status: http.StatusOK, Malformed: true})
+	}
+}
+
+// Requests returns a copy of every request received so far.
+func (s *Server) Requests() []rck.UnifiedAPIRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]rck.UnifiedAPIRequest(nil), s.requests...)
+}
+
+// RequestCount returns the number of requests received so far.
+func (s *Server) RequestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.requests)
+}
+
+func (s *Server) serveUnified(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeReply(w, Reply{Status: http.StatusMethodNotAllowed, Error: "method not allowed"})
+		return
+	}
+	req, ok := s.readRequest(w, r)
+	if !ok || !s.admit(w, r) {
+		return
+	}
+	writeReply(w, s.handlerFor(engineOf(req))(req))
+}
+
+// readRequest decodes and records a program. It reports false when it already replied.
+func (s *Server) readRequest(w http.ResponseWriter, r *http.Request) (*rck.UnifiedAPIRequest, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeReply(w, Reply{Status: http.StatusBadRequest, Error: "failed to read body", Details: err.Error()})
+		return nil, false
+	}
+	var req rck.UnifiedAPIRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeReply(w, Reply{Status: http.StatusBadRequest, Error: "invalid request", Details: err.Error()})
+		return nil, false
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+	return &req, true
+}
+
+// admit applies latency, the API key check and the next injected fault.
+// It reports false when it already replied.
+func (s *Server) admit(w http.ResponseWriter, r *http.Request) bool {
+	s.mu.Lock()
+	latency := s.latency
+	apiKey := s.apiKey
+	var fault *Fault
+	if len(s.faults) > 0 {
+		fault = &s.faults[0]
+		s.faults = s.faults[1:]
+	}
+	s.mu.Unlock()
+
+	if fault != nil {
+		latency += fault.Latency
+	}
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	if apiKey != "" && r.Header.Get("Authorization") != apiKey {
+		writeReply(w, Reply{Status: http.StatusUnauthorized, Error: "invalid API key"})
+		return false
+	}
+	if fault != nil {
+		writeReply(w, fault.reply())
+		return false
+	}
+	return true
+}
+
+// handlerFor returns the handler for an engine. Auto requests without a registered auto
+// handler are routed to the handler of the engine they resolve to.
+func (s *Server) handlerFor(engine rck.Engine) Handler {
+	s.mu.Lock()
+	handler, ok := s.handlers[engine]
+	s.mu.Unlock()
+	switch {
+	case ok:
+		return handler
+	case engine == rck.EngineAuto:
+		return func(req *rck.UnifiedAPIRequest) Reply {
+			return routeAuto(req, s.handlerFor)
+		}
+	default:
+		return DefaultHandler(engine)
+	}
+}
+
+func (f *Fault) reply() Reply {
+	status := f.Status
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+	if f.Malformed {
+		return Reply{Status: status, Header: f.Header, Body: []byte("<html>upstream error</html>")}
+	}
+	message := f.Error
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	return Reply{Status: status, Header: f.Header, Error: message, Details: "injected fault"}
+}
+
+func writeReply(w http.ResponseWriter, reply Reply) {
+	for key, values := range reply.Header {
+		for _, v := range values {
+			w.Header().Add(key, v)
+		}
+	}
+	status := reply.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if reply.Stream != nil && reply.Body == nil {
+		writeStream(w, status, reply)
+		return
+	}
+
+	body := reply.Body
+	if body == nil {
+		response := rck.UnifiedAPIResponse{Error: reply.Error, Details: reply.Details, Engine: reply.Engine}
+		if reply.Output != nil {
+			output, err := json.Marshal(reply.Output)
+			if err != nil {
+				status = http.StatusInternalServerError
+				response = rck.UnifiedAPIResponse{Error: "rcktest: failed to marshal output", Details: err.Error()}
+			} else {
+				response.Output = output
+			}
+		}
+		body, _ = json.Marshal(response)
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeStream(w http.ResponseWriter, status int, reply Reply) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	flusher, _ := w.(http.Flusher)
+
+	writeFrame := func(data string) {
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	for i, chunk := range reply.Stream {
+		if i > 0 && reply.StreamDelay > 0 {
+			time.Sleep(reply.StreamDelay)
+		}
+		frame, _ := json.Marshal(map[string]string{"delta": chunk})
+		writeFrame(string(frame))
+	}
+	final, _ := json.Marshal(map[string]string{"output": strings.Join(reply.Stream, "")})
+	writeFrame(string(final))
+	writeFrame("[DONE]")
+}
+
+func engineOf(req *rck.UnifiedAPIRequest) rck.Engine {
+	if req.Config == nil || req.Config.Engine == "" {
+		return rck.EngineAuto
+	}
+	return req.Config.Engine
+}
+
+// DefaultHandler returns the built-in handler for an engine:
+//   - standard: output synthesized from OutputDataClass
+//   - attractor: a copy of the first example's output
+//   - pure: a short text echoing the input
+//   - image: a single fake PNG data URL
+//   - auto: routed to one of the above by the rules of rck.Kernel.PlanAuto
+func DefaultHandler(engine rck.Engine) Handler {
+	switch engine {
+	case rck.EngineStandard:
+		return standardHandler
+	case rck.EngineAttractor:
+		return attractorHandler
+	case rck.EnginePure:
+		return pureHandler
+	case rck.EngineImage:
+		return imageHandler
+	case rck.EngineAuto:
+		return autoHandler
+	default:
+		return func(req *rck.UnifiedAPIRequest) Reply {
+			return Reply{Status: http.StatusBadRequest, Error: "unknown engine", Details: string(engine)}
+		}
+	}
+}
+
+func standardHandler(req *rck.UnifiedAPIRequest) Reply {
+	output, err := Synthesize(req.Program.Pipeline.OutputDataClass)
+	if err != nil {
+		return Reply{Status: http.StatusBadRequest, Error: "invalid OutputDataClass", Details: err.Error()}
+	}
+	return Reply{Output: output}
+}
+
+func attractorHandler(req *rck.UnifiedAPIRequest) Reply {
+	if len(req.Program.Pipeline.Examples) == 0 {
+		return Reply{Status: http.StatusBadRequest, Error: "Examples are required"}
+	}
+	var output interface{}
+	if err := json.Unmarshal([]byte(req.Program.Pipeline.Examples[0].Output), &output); err != nil {
+		return Reply{Status: http.StatusBadRequest, Error: "invalid example output", Details: err.Error()}
+	}
+	return Reply{Output: output}
+}
+
+func pureHandler(req *rck.UnifiedAPIRequest) Reply {
+	text := fmt.Sprintf("rcktest response to: %s", req.Program.Input.Input)
+	if req.Config != nil && req.Config.Stream {
+		return Reply{Stream: strings.SplitAfter(text, " ")}
+	}
+	return Reply{Output: text}
+}
+
+func imageHandler(req *rck.UnifiedAPIRequest) Reply {
+	return Reply{Output: []string{FakeImageDataURL()}}
+}
+
+func autoHandler(req *rck.UnifiedAPIRequest) Reply {
+	return routeAuto(req, DefaultHandler)
+}
+
+// routeAuto picks an engine for an auto request and replies with the handler handlerFor returns for it.
+func routeAuto(req *rck.UnifiedAPIRequest, handlerFor func(rck.Engine) Handler) Reply {
+	pipeline := req.Program.Pipeline
+	engine := rck.EnginePure
+	switch {
+	case len(pipeline.Examples) > 0:
+		engine = rck.EngineAttractor
+	case pipeline.FrameComposition != "" || pipeline.Lighting != "" || pipeline.Style != "":
+		engine = rck.EngineImage
+	case pipeline.OutputDataClass != "":
+		engine = rck.EngineStandard
+	}
+	reply := handlerFor(engine)(req)
+	if reply.Engine == "" {
+		reply.Engine = engine
+	}
+	return reply
+}
+
+// fakePNG is a 1x1 transparent PNG.
+const fakePNG = "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAAC0lEQVR4nGNgAAIAAAUAAXpeqz8AAAAASUVORK5CYII="
+
+// FakeImageDataURL returns a data URL holding a valid 1x1 PNG image.
+func FakeImageDataURL() string {
+	return "data:image/png;base64," + fakePNG
+}
@@ -0,0 +1,32 @@
+package rcktest_test
+
+import (
+	"context"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func TestAutoRequestsUseRegisteredHandlers(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.Handle(rck.EnginePure, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		return rcktest.Reply{Output: "custom pure"}
+	})
+
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.Compute.Auto(context.Background(), rck.AutoParams{Input: "hello", FunctionLogic: "echo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := result.Text(); err != nil || text != "custom pure" {
+		t.Errorf("Text() = %q, %v, want the registered handler's output", text, err)
+	}
+	if result.Engine() != rck.EnginePure {
+		t.Errorf("Engine() = %q, want %q", result.Engine(), rck.EnginePure)
+	}
+}
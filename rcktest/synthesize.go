@@ -0,0 +1,151 @@
+package rcktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Synthesize builds a value that conforms to a JSON Schema.
+// The schema can be a JSON string or a decoded map, as accepted by OutputDataClass.
+// A string that is not JSON is treated as a free-form description and yields
+// an object with a single "result" property.
+func Synthesize(schema interface{}) (interface{}, error) {
+	var decoded interface{}
+	switch s := schema.(type) {
+	case nil:
+		return map[string]interface{}{}, nil
+	case string:
+		if strings.TrimSpace(s) == "" {
+			return map[string]interface{}{}, nil
+		}
+		if err := json.Unmarshal([]byte(s), &decoded); err != nil {
+			return map[string]interface{}{"result": "example"}, nil
+		}
+	default:
+		// Round-trip through JSON so typed maps and slices look like decoded JSON.
+		bytes, err := json.Marshal(s)
+		if err != nil {
+			return nil, fmt.Errorf("rcktest: invalid schema: %w", err)
+		}
+		if err := json.Unmarshal(bytes, &decoded); err != nil {
+			return nil, fmt.Errorf("rcktest: invalid schema: %w", err)
+		}
+	}
+	return synthesize(decoded, "value"), nil
+}
+
+func synthesize(rawSchema interface{}, name string) interface{} {
+	schema, ok := rawSchema.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if c, ok := schema["const"]; ok {
+		return c
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok && len(enum) > 0 {
+		return enum[0]
+	}
+	for _, key := range []string{"oneOf", "anyOf", "allOf"} {
+		if options, ok := schema[key].([]interface{}); ok && len(options) > 0 {
+			return synthesize(options[0], name)
+		}
+	}
+
+	switch schemaType(schema) {
+	case "object":
+		obj := map[string]interface{}{}
+		properties, _ := schema["properties"].(map[string]interface{})
+		keys := make([]string, 0, len(properties))
+		for k := range properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			obj[k] = synthesize(properties[k], k)
+		}
+		return obj
+	case "array":
+		count := 1
+		if n, ok := schema["minItems"].(float64); ok && int(n) > count {
+			count = int(n)
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			items[i] = synthesize(schema["items"], name)
+		}
+		return items
+	case "string":
+		return synthesizeString(schema, name)
+	case "integer":
+		return synthesizeNumber(schema, true)
+	case "number":
+		return synthesizeNumber(schema, false)
+	case "boolean":
+		return true
+	case "null":
+		return nil
+	default:
+		return "example " + name
+	}
+}
+
+func schemaType(schema map[string]interface{}) string {
+	switch t := schema["type"].(type) {
+	case string:
+		return t
+	case []interface{}:
+		// Prefer the first non-null type of a union.
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok && name != "null" {
+				return name
+			}
+		}
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	if _, ok := schema["items"]; ok {
+		return "array"
+	}
+	return ""
+}
+
+func synthesizeString(schema map[string]interface{}, name string) string {
+	switch schema["format"] {
+	case "date-time":
+		return "2024-01-01T00:00:00Z"
+	case "date":
+		return "2024-01-01"
+	case "email":
+		return "user@example.com"
+	case "uri":
+		return "https://example.com"
+	}
+	value := "example " + name
+	if n, ok := schema["minLength"].(float64); ok && len([]rune(value)) < int(n) {
+		value += strings.Repeat("x", int(n)-len([]rune(value)))
+	}
+	if n, ok := schema["maxLength"].(float64); ok && len([]rune(value)) > int(n) {
+		value = string([]rune(value)[:int(n)])
+	}
+	return value
+}
+
+func synthesizeNumber(schema map[string]interface{}, integer bool) interface{} {
+	value := 0.0
+	if min, ok := schema["minimum"].(float64); ok {
+		value = min
+	} else if max, ok := schema["maximum"].(float64); ok && max < 0 {
+		value = max
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		value = min + 1
+	}
+	if integer {
+		return int64(math.Ceil(value))
+	}
+	return value
+}
@@ -0,0 +1,24 @@
+// Package rckyaml reads and writes rck workflows in YAML and loads function definitions
+// and schemas from YAML files, keeping the YAML dependency out of the core package.
+package rckyaml
+
+import (
+	"fmt"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+// ParseDAG decodes a DAG written in YAML. See rck.DAG for the format.
+func ParseDAG(data []byte) (*rck.DAG, error) {
+	var dag rck.DAG
+	if err := yaml.Unmarshal(data, &dag); err != nil {
+		return nil, rck.NewValidationError("DAG", fmt.Sprintf("invalid YAML: %v", err))
+	}
+	return &dag, nil
+}
+
+// MarshalDAG encodes a DAG as YAML. Registered functions are not encoded.
+func MarshalDAG(dag *rck.DAG) ([]byte, error) {
+	return yaml.Marshal(dag)
+}
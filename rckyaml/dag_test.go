@@ -0,0 +1,60 @@
+package rckyaml_test
+
+import (
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rckyaml"
+)
+
+const reviewDAG = `
+name: review
+concurrency: 3
+nodes:
+  - id: sentiment
+    operation: analyze
+    params: {FunctionLogic: "Analyze the tone", OutputFormat: basic_analysis}
+  - id: report
+    operation: generate_text
+    depends_on: [sentiment]
+    input: "Tone: {{sentiment.emotion}}"
+    params: {FunctionLogic: "Write a short report"}
+    on_error: default
+    default: "no report"
+`
+
+func TestParseDAG(t *testing.T) {
+	dag, err := rckyaml.ParseDAG([]byte(reviewDAG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dag.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if dag.Name != "review" || dag.Concurrency != 3 || len(dag.Nodes) != 2 {
+		t.Fatalf("dag = %+v", dag)
+	}
+	report := dag.Nodes[1]
+	if report.OnError != rck.OnErrorDefault || report.Default != "no report" || report.DependsOn[0] != "sentiment" {
+		t.Errorf("report node = %+v", report)
+	}
+
+	data, err := rckyaml.MarshalDAG(dag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := rckyaml.ParseDAG(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Nodes[1].OnError != rck.OnErrorDefault || again.Nodes[0].Params["OutputFormat"] != "basic_analysis" {
+		t.Errorf("round trip lost fields: %s", data)
+	}
+}
+
+func TestParseDAGRejectsInvalidYAML(t *testing.T) {
+	_, err := rckyaml.ParseDAG([]byte("nodes: [unclosed"))
+	if _, ok := err.(*rck.ValidationError); !ok {
+		t.Errorf("error = %v, want *rck.ValidationError", err)
+	}
+}
@@ -0,0 +1,126 @@
+package rckyaml
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadSchemaDir registers every .json, .yaml and .yml file in dir with r. Files are named
+// as for rck.SchemaRegistry.LoadDir: "customer.yaml" or "customer@v2.yaml". After loading,
+// every schema's references are checked.
+func LoadSchemaDir(r *rck.SchemaRegistry, dir string) error {
+	return LoadSchemaFS(r, os.DirFS(dir), ".")
+}
+
+// LoadSchemaFS is like LoadSchemaDir for a directory of an fs.FS, such as an embed.FS.
+func LoadSchemaFS(r *rck.SchemaRegistry, fsys fs.FS, dir string) error {
+	files, err := definitionFiles(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := loadSchemaFile(r, fsys, file); err != nil {
+			return err
+		}
+	}
+	return r.Check()
+}
+
+// LoadSchemaFile registers a single .json, .yaml or .yml file, named as for LoadSchemaDir.
+// References are not checked, so files may refer to schemas loaded later; call Check when done.
+func LoadSchemaFile(r *rck.SchemaRegistry, file string) error {
+	return loadSchemaFile(r, os.DirFS(filepath.Dir(file)), filepath.Base(file))
+}
+
+func loadSchemaFile(r *rck.SchemaRegistry, fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+	if strings.ToLower(path.Ext(file)) != ".json" {
+		// Round-trip through JSON so numbers are decoded as in JSON files.
+		var decoded map[string]interface{}
+		if err := yaml.Unmarshal(data, &decoded); err != nil {
+			return rck.NewValidationError("Schema", fmt.Sprintf("%s: %v", file, err))
+		}
+		if data, err = json.Marshal(decoded); err != nil {
+			return rck.NewValidationError("Schema", fmt.Sprintf("%s: %v", file, err))
+		}
+	}
+	name, version, _ := strings.Cut(strings.TrimSuffix(path.Base(file), path.Ext(file)), "@")
+	if err := r.Register(name, version, string(data)); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// LoadFunctionDir registers the definitions of every .json, .yaml and .yml file in dir with r.
+// A file holds a single definition or a list of them; see rck.FunctionDefinition for the format.
+func LoadFunctionDir(r *rck.FunctionRegistry, dir string) error {
+	return LoadFunctionFS(r, os.DirFS(dir), ".")
+}
+
+// LoadFunctionFS is like LoadFunctionDir for a directory of an fs.FS, such as an embed.FS.
+func LoadFunctionFS(r *rck.FunctionRegistry, fsys fs.FS, dir string) error {
+	files, err := definitionFiles(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		defs, err := decodeFunctionDefinitions(data, strings.ToLower(path.Ext(file)) == ".json")
+		if err != nil {
+			return rck.NewValidationError("FunctionDefinition", fmt.Sprintf("%s: %v", file, err))
+		}
+		for _, def := range defs {
+			if err := r.Register(def); err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	return nil
+}
+
+func decodeFunctionDefinitions(data []byte, isJSON bool) ([]rck.FunctionDefinition, error) {
+	unmarshal := yaml.Unmarshal
+	if isJSON {
+		unmarshal = json.Unmarshal
+	}
+	var defs []rck.FunctionDefinition
+	if err := unmarshal(data, &defs); err == nil {
+		return defs, nil
+	}
+	var def rck.FunctionDefinition
+	if err := unmarshal(data, &def); err != nil {
+		return nil, err
+	}
+	return []rck.FunctionDefinition{def}, nil
+}
+
+// definitionFiles lists the .json, .yaml and .yml files of dir.
+func definitionFiles(fsys fs.FS, dir string) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		files = append(files, path.Join(dir, entry.Name()))
+	}
+	return files, nil
+}
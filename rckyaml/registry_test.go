@@ -0,0 +1,91 @@
+package rckyaml_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rckyaml"
+)
+
+func TestLoadSchemaFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"schemas/address.yaml":     {Data: []byte("type: object\nproperties:\n  city: {type: string, maxLength: 40}\n")},
+		"schemas/customer@v2.json": {Data: []byte(`{"type": "object", "properties": {"address": {"$ref": "address"}}}`)},
+		"schemas/notes.txt":        {Data: []byte("ignored")},
+	}
+	registry := rck.NewSchemaRegistry()
+	if err := rckyaml.LoadSchemaFS(registry, fsys, "schemas"); err != nil {
+		t.Fatal(err)
+	}
+	if names := registry.Names(); len(names) != 2 || names[0] != "address" || names[1] != "customer" {
+		t.Fatalf("names = %v", names)
+	}
+	schema, err := registry.Schema("customer@v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"properties":{"address":{"properties":{"city":{"maxLength":40,"type":"string"}},"type":"object"}},"type":"object"}`
+	if schema != want {
+		t.Errorf("schema = %s, want %s", schema, want)
+	}
+}
+
+func TestLoadSchemaFSRejectsBrokenReference(t *testing.T) {
+	fsys := fstest.MapFS{"customer.yml": {Data: []byte("type: object\nproperties:\n  address: {$ref: address}\n")}}
+	if err := rckyaml.LoadSchemaFS(rck.NewSchemaRegistry(), fsys, "."); err == nil {
+		t.Error("loaded a schema with an unknown reference")
+	}
+}
+
+func TestLoadFunctionFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"extract.yaml": {Data: []byte(`
+name: extract_customer
+version: v3
+function_logic: Extract the customer's name
+output_data_class:
+  type: object
+  properties:
+    name: {type: string}
+config: {temperature: 0}
+`)},
+		"more.yml": {Data: []byte(`
+- name: summarize
+  function_logic: Summarize
+- name: classify
+  examples:
+    - input: great
+      output: {label: positive}
+`)},
+		"echo.json": {Data: []byte(`{"name": "echo", "function_logic": "Repeat the input"}`)},
+	}
+	registry := rck.NewFunctionRegistry(nil)
+	if err := rckyaml.LoadFunctionFS(registry, fsys, "."); err != nil {
+		t.Fatal(err)
+	}
+	if names := registry.Names(); len(names) != 4 {
+		t.Fatalf("names = %v", names)
+	}
+	def, err := registry.Get("extract_customer@v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def.Config == nil || def.Config.Temperature == nil || *def.Config.Temperature != 0 || def.OutputDataClass == nil {
+		t.Errorf("definition = %+v", def)
+	}
+	classify, err := registry.Get("classify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(classify.Examples) != 1 || classify.Examples[0].Output["label"] != "positive" {
+		t.Errorf("examples = %+v", classify.Examples)
+	}
+}
+
+func TestLoadFunctionFSRejectsInvalidYAML(t *testing.T) {
+	fsys := fstest.MapFS{"bad.yaml": {Data: []byte("name: [unclosed")}}
+	if err := rckyaml.LoadFunctionFS(rck.NewFunctionRegistry(nil), fsys, "."); err == nil {
+		t.Error("loaded invalid YAML")
+	}
+}
@@ -0,0 +1,86 @@
+package rck_test
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+const nameSchema = `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`
+
+// newRepairServer returns a server whose first standard-engine reply violates nameSchema.
+func newRepairServer(t *testing.T) *rcktest.Server {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	var calls atomic.Int64
+	server.Handle(rck.EngineStandard, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		if calls.Add(1) == 1 {
+			return rcktest.Reply{Output: map[string]interface{}{}}
+		}
+		return rcktest.Reply{Output: map[string]interface{}{"name": "Ada"}}
+	})
+	return server
+}
+
+func TestRepairKeepsCallerFeedback(t *testing.T) {
+	server := newRepairServer(t)
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := client.Compute.StructuredTransform(context.Background(), rck.StructuredTransformParams{
+		Input:           "Ada Lovelace",
+		FunctionLogic:   "extract the name",
+		OutputDataClass: nameSchema,
+		CustomLogic:     map[string]string{"repair_feedback": "Use the full first name."},
+		MaxRepairRounds: 1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RepairRounds() != 1 {
+		t.Errorf("RepairRounds() = %d, want 1", result.RepairRounds())
+	}
+
+	requests := server.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("server received %d requests, want 2", len(requests))
+	}
+	feedback := requests[1].Program.Pipeline.CustomLogic["repair_feedback"]
+	if !strings.HasPrefix(feedback, "Use the full first name.") || !strings.Contains(feedback, "did not conform") {
+		t.Errorf("repair_feedback = %q, want the caller's text followed by the violations", feedback)
+	}
+}
+
+func TestInvalidResponsesAreNotCached(t *testing.T) {
+	server := newRepairServer(t)
+	options := server.ClientOptions()
+	options.Cache = &rck.CacheOptions{Backend: rck.NewMemoryCache(0, 0)}
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := rck.StructuredTransformParams{
+		Input:           "Ada Lovelace",
+		FunctionLogic:   "extract the name",
+		OutputDataClass: nameSchema,
+		MaxRepairRounds: 1,
+	}
+	if _, err := client.Compute.StructuredTransform(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+
+	// The invalid first response must not be served again.
+	result, err := client.Compute.StructuredTransform(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RepairRounds() != 0 {
+		t.Errorf("RepairRounds() = %d, want 0", result.RepairRounds())
+	}
+}
@@ -0,0 +1,122 @@
+package rck
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how HttpClient retries failed requests.
+// A nil policy, or one with MaxAttempts <= 1, disables retries.
+type RetryPolicy struct {
+	MaxAttempts          int           // Total attempts including the first one
+	BaseDelay            time.Duration // Delay before the first retry, doubled on each subsequent retry
+	MaxDelay             time.Duration // Upper bound for a single backoff delay (0 means no bound)
+	Jitter               float64       // Fraction of each delay that is randomized, between 0 and 1
+	RetryableStatusCodes []int         // HTTP status codes that trigger a retry
+	RetryNetworkErrors   bool          // Retry on transport failures (connection reset, DNS, body read...)
+	RespectRetryAfter    bool          // Use the server's Retry-After header when present, up to MaxDelay
+}
+
+// DefaultRetryPolicy returns a retry policy suitable for most workloads:
+// 3 attempts, exponential backoff from 500ms up to 10s with 20% jitter,
+// retrying on 408, 429, 500, 502, 503, 504 and network errors.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusRequestTimeout,
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryNetworkErrors: true,
+		RespectRetryAfter:  true,
+	}
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) retryableStatus(statusCode int) bool {
+	for _, code := range p.RetryableStatusCodes {
+		if code == statusCode {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the given retry (1 for the first retry).
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(retry-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay = delay*(1-jitter) + delay*jitter*rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// delay returns the wait before the given retry: the server's Retry-After when respected,
+// or else the backoff. Either is capped at MaxDelay so a server cannot stall the client.
+func (p *RetryPolicy) delay(retry int, header http.Header) time.Duration {
+	if p.RespectRetryAfter {
+		if retryAfter, ok := parseRetryAfter(header); ok {
+			if p.MaxDelay > 0 && retryAfter > p.MaxDelay {
+				return p.MaxDelay
+			}
+			return retryAfter
+		}
+	}
+	return p.backoff(retry)
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for the delay to elapse. It returns false without waiting
+// when the context's deadline would expire first, or when the context is done.
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+		return false
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,131 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func fastRetryPolicy() *rck.RetryPolicy {
+	policy := rck.DefaultRetryPolicy()
+	policy.BaseDelay = time.Millisecond
+	policy.MaxDelay = 50 * time.Millisecond
+	policy.Jitter = 0
+	return policy
+}
+
+func callWithRetry(t *testing.T, server *rcktest.Server, policy *rck.RetryPolicy) error {
+	t.Helper()
+	options := server.ClientOptions()
+	options.Retry = policy
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Compute.GenerateText(context.Background(), rck.GenerateTextParams{Input: "hello", FunctionLogic: "echo"})
+	return err
+}
+
+func TestRetryStatusCodes(t *testing.T) {
+	emptyCodes := fastRetryPolicy()
+	emptyCodes.RetryableStatusCodes = nil
+
+	tests := []struct {
+		name     string
+		policy   *rck.RetryPolicy
+		failures int
+		status   int
+		wantErr  bool
+		want     int // Requests the server receives
+	}{
+		{"recovers", fastRetryPolicy(), 2, http.StatusServiceUnavailable, false, 3},
+		{"gives up after MaxAttempts", fastRetryPolicy(), 5, http.StatusServiceUnavailable, true, 3},
+		{"status not listed", fastRetryPolicy(), 1, http.StatusBadRequest, true, 1},
+		{"no listed statuses", emptyCodes, 1, http.StatusServiceUnavailable, true, 1},
+		{"nil policy", nil, 1, http.StatusServiceUnavailable, true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := rcktest.NewServer()
+			defer server.Close()
+			server.FailNext(tt.failures, tt.status)
+
+			err := callWithRetry(t, server, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got := server.RequestCount(); got != tt.want {
+				t.Errorf("server received %d requests, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryQuotaExceededIsNotRetried(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.InjectFault(rcktest.Fault{Status: http.StatusTooManyRequests, Error: "monthly quota exhausted"})
+
+	err := callWithRetry(t, server, fastRetryPolicy())
+	if !errors.Is(err, rck.ErrQuotaExceeded) {
+		t.Errorf("error = %v, want ErrQuotaExceeded", err)
+	}
+	if got := server.RequestCount(); got != 1 {
+		t.Errorf("server received %d requests, want 1", got)
+	}
+}
+
+func TestRetryBackoffDoubles(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.FailNext(2, http.StatusServiceUnavailable)
+
+	policy := fastRetryPolicy()
+	policy.BaseDelay = 20 * time.Millisecond
+	start := time.Now()
+	if err := callWithRetry(t, server, policy); err != nil {
+		t.Fatal(err)
+	}
+	// 20ms before the first retry and 40ms before the second.
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("retries took %s, want at least 60ms", elapsed)
+	}
+}
+
+func TestRetryAfterIsCappedByMaxDelay(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.InjectFault(rcktest.Fault{Status: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": {"30"}}})
+
+	start := time.Now()
+	if err := callWithRetry(t, server, fastRetryPolicy()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retry waited %s, want at most MaxDelay", elapsed)
+	}
+	if got := server.RequestCount(); got != 2 {
+		t.Errorf("server received %d requests, want 2", got)
+	}
+}
+
+func TestRetryAfterIsRespected(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.InjectFault(rcktest.Fault{Status: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"1"}}})
+
+	policy := fastRetryPolicy()
+	policy.MaxDelay = 2 * time.Second
+	start := time.Now()
+	if err := callWithRetry(t, server, policy); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retry waited %s, want the server's 1s", elapsed)
+	}
+}
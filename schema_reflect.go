@@ -0,0 +1,259 @@
+package rck
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// SchemaFor derives a JSON Schema from the Go type T, suitable for OutputDataClass.
+//
+// Struct fields are named after their `json` tag and described by an `rck` (or
+// `jsonschema`) tag holding comma-separated options:
+//
+//	Name   string   `json:"name" rck:"description=Customer name"`
+//	Level  string   `json:"level" rck:"enum=gold|silver|bronze"`
+//	Age    *int     `json:"age" rck:"minimum=0,maximum=150"`
+//	Tags   []string `json:"tags,omitempty" rck:"required"`
+//
+// Supported options are description, title, format, pattern, enum (values
+// separated by '|'), minimum, maximum, minLength, maxLength, minItems,
+// maxItems, required and optional. Descriptions containing commas can be
+// given in a separate `description` tag instead.
+//
+// Fields are required unless they are pointers, marked omitempty, or tagged
+// optional. time.Time maps to a date-time string, maps to objects with
+// additionalProperties, and slices/arrays to arrays.
+func SchemaFor[T any]() (map[string]interface{}, error) {
+	return reflectSchema(reflect.TypeOf((*T)(nil)).Elem(), map[reflect.Type]bool{})
+}
+
+func reflectSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]interface{}, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case t == rawMessageType:
+		return map[string]interface{}{}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return map[string]interface{}{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := reflectSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("rck: unsupported map key type %s in schema", t.Key())
+		}
+		values, err := reflectSchema(t.Elem(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if visiting[t] {
+			return nil, fmt.Errorf("rck: recursive type %s cannot be expressed as a schema", t)
+		}
+		visiting[t] = true
+		defer delete(visiting, t)
+		return reflectStructSchema(t, visiting)
+	default:
+		return nil, fmt.Errorf("rck: unsupported type %s in schema", t)
+	}
+}
+
+func reflectStructSchema(t reflect.Type, visiting map[reflect.Type]bool) (map[string]interface{}, error) {
+	properties := map[string]interface{}{}
+	required := []string{}
+	if err := collectFields(t, visiting, properties, &required); err != nil {
+		return nil, err
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema, nil
+}
+
+func collectFields(t reflect.Type, visiting map[reflect.Type]bool, properties map[string]interface{}, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		jsonTag := field.Tag.Get("json")
+		if jsonTag == "-" {
+			continue
+		}
+		name, jsonOpts, _ := strings.Cut(jsonTag, ",")
+
+		// Embedded structs without an explicit name are flattened, like encoding/json does.
+		// The fields of a nil embedded pointer are omitted, so they are all optional.
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			embeddedRequired := required
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+				embeddedRequired = new([]string)
+			}
+			if ft.Kind() == reflect.Struct && ft != timeType {
+				if err := collectFields(ft, visiting, properties, embeddedRequired); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop, err := reflectSchema(field.Type, visiting)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		// Copy so per-field options never leak into the shared element schema.
+		fieldSchema := make(map[string]interface{}, len(prop)+2)
+		for k, v := range prop {
+			fieldSchema[k] = v
+		}
+
+		isRequired := field.Type.Kind() != reflect.Pointer && !strings.Contains(jsonOpts, "omitempty")
+		tag := field.Tag.Get("rck")
+		if tag == "" {
+			tag = field.Tag.Get("jsonschema")
+		}
+		explicit, err := applySchemaTag(fieldSchema, tag, field.Type)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+		if explicit != nil {
+			isRequired = *explicit
+		}
+		if desc := field.Tag.Get("description"); desc != "" {
+			fieldSchema["description"] = desc
+		}
+
+		properties[name] = fieldSchema
+		if isRequired {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// applySchemaTag applies the options of an rck/jsonschema tag to schema.
+// It returns a non-nil bool when the tag explicitly marks the field required or optional.
+func applySchemaTag(schema map[string]interface{}, tag string, fieldType reflect.Type) (*bool, error) {
+	var explicit *bool
+	if tag == "" {
+		return nil, nil
+	}
+	for _, opt := range strings.Split(tag, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		switch key {
+		case "":
+			continue
+		case "required", "optional":
+			v := key == "required"
+			explicit = &v
+		case "description", "title", "format", "pattern":
+			schema[key] = value
+		case "enum":
+			values, err := parseEnumValues(value, fieldType)
+			if err != nil {
+				return nil, err
+			}
+			// Enums on slices constrain the elements rather than the array itself.
+			if items, ok := schema["items"].(map[string]interface{}); ok && schema["type"] == "array" {
+				elem := make(map[string]interface{}, len(items)+1)
+				for k, v := range items {
+					elem[k] = v
+				}
+				elem["enum"] = values
+				schema["items"] = elem
+			} else {
+				schema["enum"] = values
+			}
+		case "minimum", "maximum":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			schema[key] = n
+		case "minLength", "maxLength", "minItems", "maxItems":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q", key, value)
+			}
+			schema[key] = n
+		default:
+			return nil, fmt.Errorf("unknown schema tag option %q", key)
+		}
+	}
+	return explicit, nil
+}
+
+func parseEnumValues(value string, fieldType reflect.Type) ([]interface{}, error) {
+	for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array {
+		fieldType = fieldType.Elem()
+	}
+	parts := strings.Split(value, "|")
+	values := make([]interface{}, 0, len(parts))
+	for _, part := range parts {
+		switch fieldType.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid integer enum value %q", part)
+			}
+			values = append(values, n)
+		case reflect.Float32, reflect.Float64:
+			n, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number enum value %q", part)
+			}
+			values = append(values, n)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid boolean enum value %q", part)
+			}
+			values = append(values, b)
+		default:
+			values = append(values, part)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,44 @@
+package rck_test
+
+import (
+	"sort"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+type Audit struct {
+	CreatedBy string `json:"created_by"`
+}
+
+type Contact struct {
+	Phone string `json:"phone"`
+}
+
+type Customer struct {
+	Audit
+	*Contact
+	Name     string  `json:"name"`
+	Nickname *string `json:"nickname"`
+	Notes    string  `json:"notes,omitempty"`
+}
+
+func TestSchemaForRequiredFields(t *testing.T) {
+	schema, err := rck.SchemaFor[Customer]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	properties := schema["properties"].(map[string]interface{})
+	for _, name := range []string{"created_by", "phone", "name", "nickname", "notes"} {
+		if _, ok := properties[name]; !ok {
+			t.Errorf("property %q is missing", name)
+		}
+	}
+
+	required, _ := schema["required"].([]string)
+	sort.Strings(required)
+	want := []string{"created_by", "name"}
+	if len(required) != len(want) || required[0] != want[0] || required[1] != want[1] {
+		t.Errorf("required = %v, want %v", required, want)
+	}
+}
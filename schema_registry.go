@@ -0,0 +1,432 @@
+package rck
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SchemaRegistry holds named, versioned JSON Schemas for use as OutputDataClass.
+// It is safe for concurrent use.
+//
+// Schemas can refer to each other with "$ref": a reference is either a JSON pointer into
+// the same schema ("#/$defs/address") or a registered schema, optionally versioned and
+// followed by a pointer ("address", "address@v2", "common#/$defs/phone"). References are
+// inlined when a schema is looked up; recursive references are rejected.
+type SchemaRegistry struct {
+	mu      sync.RWMutex
+	schemas versioned[*registeredSchema]
+}
+
+type registeredSchema struct {
+	name       string
+	version    string
+	schema     map[string]interface{}
+	predefined bool // One of predefinedSchemas, whose original text is kept
+}
+
+// DefaultSchemas is the registry used by Kernel.Analyze and Kernel.Translate unless ClientOptions.Schemas is set.
+// It starts with the predefined schemas basic_analysis, poem_creation, scene_description and translation.
+var DefaultSchemas = newDefaultSchemaRegistry()
+
+func newDefaultSchemaRegistry() *SchemaRegistry {
+	r := NewSchemaRegistry()
+	for name, schema := range predefinedSchemas {
+		if err := r.Register(name, "", schema); err != nil {
+			panic("rck: invalid predefined schema " + name + ": " + err.Error())
+		}
+		r.schemas.items[name][0].predefined = true
+	}
+	return r
+}
+
+// NewSchemaRegistry creates an empty registry.
+func NewSchemaRegistry() *SchemaRegistry {
+	return &SchemaRegistry{schemas: newVersioned("schema", func(s *registeredSchema) string { return s.version })}
+}
+
+// Register checks that schema is a well-formed JSON Schema and stores it under name and version.
+// The schema can be a JSON string or a map, as for OutputDataClass. A name and version can be
+// registered only once. References to other schemas are checked when the schema is looked up.
+func (r *SchemaRegistry) Register(name, version string, schema interface{}) error {
+	ref := promptRef(name, version)
+	if name == "" || strings.ContainsAny(name, "@#") || strings.ContainsAny(version, "@#") {
+		return NewValidationError("Schema", fmt.Sprintf("invalid schema name %q", ref))
+	}
+	parsed, err := parseSchema(schema)
+	if err != nil {
+		return NewValidationError("Schema", fmt.Sprintf("%s: not a JSON object", ref))
+	}
+	if err := checkSchema(parsed, ""); err != nil {
+		return NewValidationError("Schema", fmt.Sprintf("%s: %v", ref, err))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.schemas.add(name, &registeredSchema{name: name, version: version, schema: parsed}) {
+		return NewValidationError("Schema", fmt.Sprintf("%s is already registered", ref))
+	}
+	return nil
+}
+
+// LoadDir registers every .json file in dir. The file name gives the schema name and
+// optional version: "customer.json" or "customer@v2.json". After loading, every schema's
+// references are checked. Package rckyaml loads YAML files as well.
+func (r *SchemaRegistry) LoadDir(dir string) error {
+	return r.LoadFS(os.DirFS(dir), ".")
+}
+
+// LoadFS is like LoadDir for a directory of an fs.FS, such as an embed.FS.
+func (r *SchemaRegistry) LoadFS(fsys fs.FS, dir string) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		ext := strings.ToLower(path.Ext(entry.Name()))
+		if entry.IsDir() || ext != ".json" {
+			continue
+		}
+		if err := r.loadFile(fsys, path.Join(dir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return r.Check()
+}
+
+// LoadFile registers a single .json file, named as for LoadDir.
+// References are not checked, so files may refer to schemas loaded later; call Check when done.
+func (r *SchemaRegistry) LoadFile(file string) error {
+	return r.loadFile(os.DirFS(filepath.Dir(file)), filepath.Base(file))
+}
+
+func (r *SchemaRegistry) loadFile(fsys fs.FS, file string) error {
+	data, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return err
+	}
+	name, version, _ := strings.Cut(strings.TrimSuffix(path.Base(file), path.Ext(file)), "@")
+	if err := r.Register(name, version, string(data)); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// Check resolves every registered schema and reports the first broken or recursive reference.
+func (r *SchemaRegistry) Check() error {
+	for _, name := range r.Names() {
+		for _, version := range r.Versions(name) {
+			if _, err := r.SchemaMap(promptRef(name, version)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Has reports whether a schema is registered for "name@version", or for a bare name.
+func (r *SchemaRegistry) Has(ref string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, err := r.lookup(ref)
+	return err == nil
+}
+
+// Names returns the names of the registered schemas in sorted order.
+func (r *SchemaRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.schemas.names()
+}
+
+// Versions returns the registered versions of a schema, oldest first.
+func (r *SchemaRegistry) Versions(name string) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.schemas.versions(name)
+}
+
+// Schema returns the schema for "name@version", or the latest version for a bare name,
+// as a JSON string with all references inlined. A JSON pointer can follow the reference
+// to select a subschema, as in "customer@v2#/properties/address".
+func (r *SchemaRegistry) Schema(ref string) (string, error) {
+	schema, err := r.SchemaMap(ref)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// text is like Schema but returns predefined schemas in their original formatting.
+func (r *SchemaRegistry) text(ref string) (string, error) {
+	r.mu.RLock()
+	schema, err := r.lookup(ref)
+	r.mu.RUnlock()
+	if err == nil && schema.predefined {
+		return predefinedSchemas[schema.name], nil
+	}
+	return r.Schema(ref)
+}
+
+// SchemaMap is like Schema but returns the schema as a map.
+func (r *SchemaRegistry) SchemaMap(ref string) (map[string]interface{}, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	// Look the schema up first so an unknown name is not reported as a broken $ref.
+	name, _, _ := strings.Cut(ref, "#")
+	if _, err := r.lookup(name); err != nil {
+		return nil, NewValidationError("Schema", err.Error())
+	}
+	resolver := &schemaResolver{registry: r, active: map[string]bool{}}
+	resolved, err := resolver.resolveRef(ref, nil)
+	if err != nil {
+		return nil, NewValidationError("Schema", fmt.Sprintf("%s: %v", ref, err))
+	}
+	schema, ok := resolved.(map[string]interface{})
+	if !ok {
+		return nil, NewValidationError("Schema", fmt.Sprintf("%s is not an object schema", ref))
+	}
+	return schema, nil
+}
+
+// lookup finds a registered schema. The caller must hold r.mu.
+func (r *SchemaRegistry) lookup(ref string) (*registeredSchema, error) {
+	return r.schemas.get(ref)
+}
+
+// schemaResolver inlines $ref references. The caller must hold the registry's read lock.
+type schemaResolver struct {
+	registry *SchemaRegistry
+	active   map[string]bool // References being resolved, to detect recursion
+}
+
+// resolve returns a copy of node with references inlined. doc is the schema node belongs to.
+func (s *schemaResolver) resolve(node interface{}, doc *registeredSchema) (interface{}, error) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		ref, hasRef := n["$ref"].(string)
+		out := make(map[string]interface{}, len(n))
+		for key, value := range n {
+			if key == "$ref" || key == "$defs" || key == "definitions" {
+				continue // Definitions are only reachable through references, which are inlined.
+			}
+			resolved, err := s.resolve(value, doc)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		if !hasRef {
+			return out, nil
+		}
+
+		target, err := s.resolveRef(ref, doc)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) == 0 {
+			return target, nil
+		}
+		// Keywords next to $ref apply in addition to the referenced schema.
+		out["allOf"] = append([]interface{}{target}, sliceOrEmpty(out["allOf"])...)
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(n))
+		for i, value := range n {
+			resolved, err := s.resolve(value, doc)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return node, nil
+	}
+}
+
+func (s *schemaResolver) resolveRef(ref string, doc *registeredSchema) (interface{}, error) {
+	target, pointer, _ := strings.Cut(ref, "#")
+	if target != "" {
+		if strings.Contains(target, "://") {
+			return nil, fmt.Errorf("$ref %q: remote references are not supported", ref)
+		}
+		registered, err := s.registry.lookup(target)
+		if err != nil {
+			return nil, fmt.Errorf("$ref %q: %v", ref, err)
+		}
+		doc = registered
+	}
+
+	key := promptRef(doc.name, doc.version) + "#" + pointer
+	if s.active[key] {
+		return nil, fmt.Errorf("$ref %q is recursive", ref)
+	}
+	node, err := resolvePointer(doc.schema, pointer)
+	if err != nil {
+		return nil, fmt.Errorf("$ref %q: %v", ref, err)
+	}
+
+	s.active[key] = true
+	defer delete(s.active, key)
+	return s.resolve(node, doc)
+}
+
+// resolvePointer follows a JSON pointer such as "/$defs/address" from root.
+func resolvePointer(root interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return root, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	node := root
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		switch n := node.(type) {
+		case map[string]interface{}:
+			value, ok := n[token]
+			if !ok {
+				return nil, fmt.Errorf("JSON pointer %q: %q not found", pointer, token)
+			}
+			node = value
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(n) {
+				return nil, fmt.Errorf("JSON pointer %q: invalid index %q", pointer, token)
+			}
+			node = n[index]
+		default:
+			return nil, fmt.Errorf("JSON pointer %q: %q is not inside an object or array", pointer, token)
+		}
+	}
+	return node, nil
+}
+
+func sliceOrEmpty(value interface{}) []interface{} {
+	items, _ := value.([]interface{})
+	return items
+}
+
+var schemaTypeNames = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true,
+	"integer": true, "boolean": true, "null": true,
+}
+
+// checkSchema reports the first keyword of a schema that is not well-formed.
+func checkSchema(node interface{}, path string) error {
+	schema, ok := node.(map[string]interface{})
+	if !ok {
+		if _, isBool := node.(bool); isBool {
+			return nil
+		}
+		return fmt.Errorf("%s: a schema must be an object or a boolean", pointerOrRoot(path))
+	}
+
+	for key, value := range schema {
+		at := joinPointer(path, key)
+		fail := func(format string, args ...interface{}) error {
+			return fmt.Errorf("%s: %s", at, fmt.Sprintf(format, args...))
+		}
+		switch key {
+		case "type":
+			types := []interface{}{value}
+			if list, ok := value.([]interface{}); ok {
+				types = list
+			}
+			for _, t := range types {
+				if name, ok := t.(string); !ok || !schemaTypeNames[name] {
+					return fail("unknown type %v", t)
+				}
+			}
+		case "properties", "$defs", "definitions", "patternProperties":
+			props, ok := value.(map[string]interface{})
+			if !ok {
+				return fail("must be an object")
+			}
+			for name, sub := range props {
+				if err := checkSchema(sub, joinPointer(at, name)); err != nil {
+					return err
+				}
+			}
+		case "required":
+			list, ok := value.([]interface{})
+			if !ok {
+				return fail("must be an array of strings")
+			}
+			for _, item := range list {
+				if _, ok := item.(string); !ok {
+					return fail("must be an array of strings")
+				}
+			}
+		case "items":
+			if list, ok := value.([]interface{}); ok {
+				for i, sub := range list {
+					if err := checkSchema(sub, joinPointer(at, strconv.Itoa(i))); err != nil {
+						return err
+					}
+				}
+			} else if err := checkSchema(value, at); err != nil {
+				return err
+			}
+		case "additionalProperties", "not", "contains", "propertyNames":
+			if err := checkSchema(value, at); err != nil {
+				return err
+			}
+		case "allOf", "anyOf", "oneOf", "prefixItems":
+			list, ok := value.([]interface{})
+			if !ok || len(list) == 0 {
+				return fail("must be a non-empty array of schemas")
+			}
+			for i, sub := range list {
+				if err := checkSchema(sub, joinPointer(at, strconv.Itoa(i))); err != nil {
+					return err
+				}
+			}
+		case "enum":
+			if _, ok := value.([]interface{}); !ok {
+				return fail("must be an array")
+			}
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+			if _, ok := value.(float64); !ok {
+				return fail("must be a number")
+			}
+		case "minLength", "maxLength", "minItems", "maxItems", "minProperties", "maxProperties":
+			n, ok := value.(float64)
+			if !ok || n < 0 || n != float64(int(n)) {
+				return fail("must be a non-negative integer")
+			}
+		case "pattern":
+			pattern, ok := value.(string)
+			if !ok {
+				return fail("must be a string")
+			}
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fail("invalid pattern: %v", err)
+			}
+		case "$ref":
+			if _, ok := value.(string); !ok {
+				return fail("must be a string")
+			}
+		}
+	}
+	return nil
+}
+
+func pointerOrRoot(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
@@ -0,0 +1,71 @@
+package rck_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func translateWith(t *testing.T, registry *rck.SchemaRegistry) string {
+	t.Helper()
+	server := rcktest.NewServer()
+	defer server.Close()
+	options := server.ClientOptions()
+	options.Schemas = registry
+	client, err := rck.NewClient("test-key", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Compute.Translate(context.Background(), rck.TranslateParams{Input: "hallo", TargetLanguage: "English"}); err != nil {
+		t.Fatal(err)
+	}
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(requests))
+	}
+	return requests[0].Program.Pipeline.OutputDataClass
+}
+
+func TestTranslateUsesKernelSchemas(t *testing.T) {
+	registry := rck.NewSchemaRegistry()
+	err := registry.Register("translation", "", `{"type": "object", "properties": {"glossed": {"type": "string"}}, "required": ["glossed"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema := translateWith(t, registry); !strings.Contains(schema, "glossed") {
+		t.Errorf("OutputDataClass = %s, want the registry's translation schema", schema)
+	}
+}
+
+func TestTranslateFallsBackToPredefinedSchema(t *testing.T) {
+	want, _ := rck.GetPredefinedSchema("translation")
+	for name, registry := range map[string]*rck.SchemaRegistry{"default": nil, "empty": rck.NewSchemaRegistry()} {
+		if schema := translateWith(t, registry); schema != want {
+			t.Errorf("%s registry: OutputDataClass = %s, want the predefined schema", name, schema)
+		}
+	}
+}
+
+func TestAnalyzeSendsPredefinedSchemaText(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := rck.AnalyzeParams{Input: "a poem", FunctionLogic: "analyze", OutputFormat: "basic_analysis"}
+	if _, err := client.Compute.Analyze(context.Background(), params); err != nil {
+		t.Fatal(err)
+	}
+	want, _ := rck.GetPredefinedSchema("basic_analysis")
+	requests := server.Requests()
+	if len(requests) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(requests))
+	}
+	if schema := requests[0].Program.Pipeline.OutputDataClass; schema != want {
+		t.Errorf("OutputDataClass = %s, want the predefined schema as written", schema)
+	}
+}
@@ -0,0 +1,370 @@
+package rck
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes a single place where data does not conform to a schema.
+type SchemaViolation struct {
+	Path    string // JSON pointer to the offending value, "" for the document root
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "/"
+	}
+	return path + ": " + v.Message
+}
+
+// SchemaViolationError is returned when output does not conform to the requested OutputDataClass.
+type SchemaViolationError struct {
+	Violations []SchemaViolation
+	Output     json.RawMessage // The output that failed validation
+}
+
+func (e *SchemaViolationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.String()
+	}
+	return fmt.Sprintf("schema validation failed: %s", strings.Join(parts, "; "))
+}
+
+// ValidateJSON checks data against a JSON Schema.
+// The schema can be given in any form accepted by OutputDataClass: a JSON string
+// or a map[string]interface{}. It supports the following subset of draft 2020-12:
+// type, properties, required, additionalProperties, enum, const, items, minItems,
+// maxItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum,
+// exclusiveMaximum, allOf, anyOf and oneOf. A *SchemaViolationError listing every
+// failure is returned when the data does not conform.
+func ValidateJSON(schema interface{}, data []byte) error {
+	compiled, err := parseSchema(schema)
+	if err != nil {
+		return err
+	}
+	return validateAgainst(compiled, data)
+}
+
+func parseSchema(schema interface{}) (map[string]interface{}, error) {
+	schemaStr, err := stringifyOutputClass(schema)
+	if err != nil {
+		return nil, err
+	}
+	var compiled map[string]interface{}
+	if err := json.Unmarshal([]byte(schemaStr), &compiled); err != nil {
+		return nil, NewValidationError("OutputDataClass", "is not a JSON schema and cannot be used for validation")
+	}
+	return compiled, nil
+}
+
+func validateAgainst(schema map[string]interface{}, data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return &SchemaViolationError{
+			Violations: []SchemaViolation{{Path: "", Message: "output is not valid JSON: " + err.Error()}},
+			Output:     json.RawMessage(data),
+		}
+	}
+
+	v := &schemaValidator{}
+	v.validate(schema, value, "")
+	if len(v.violations) > 0 {
+		return &SchemaViolationError{Violations: v.violations, Output: json.RawMessage(data)}
+	}
+	return nil
+}
+
+type schemaValidator struct {
+	violations []SchemaViolation
+}
+
+func (v *schemaValidator) fail(path, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// check runs a nested validation without recording its violations and reports whether it passed.
+func (v *schemaValidator) check(schema interface{}, value interface{}, path string) bool {
+	sub := &schemaValidator{}
+	sub.validate(schema, value, path)
+	return len(sub.violations) == 0
+}
+
+func (v *schemaValidator) validate(rawSchema interface{}, value interface{}, path string) {
+	switch s := rawSchema.(type) {
+	case bool:
+		if !s {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	case map[string]interface{}:
+		v.validateObjectSchema(s, value, path)
+	}
+}
+
+func (v *schemaValidator) validateObjectSchema(schema map[string]interface{}, value interface{}, path string) {
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", describeType(t), jsonTypeOf(value))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range enum {
+			if jsonEqual(candidate, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "value %s is not one of the allowed values", compactJSON(value))
+		}
+	}
+	if c, ok := schema["const"]; ok && !jsonEqual(c, value) {
+		v.fail(path, "value must be %s", compactJSON(c))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, val, path)
+	case []interface{}:
+		v.validateArray(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case json.Number:
+		v.validateNumber(schema, val, path)
+	}
+
+	if all, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.validate(sub, value, path)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.check(sub, value, path) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(path, "value does not match any of the anyOf schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, sub := range oneOf {
+			if v.check(sub, value, path) {
+				matches++
+			}
+		}
+		if matches != 1 {
+			v.fail(path, "value must match exactly one oneOf schema, matched %d", matches)
+		}
+	}
+}
+
+func (v *schemaValidator) validateObject(schema map[string]interface{}, obj map[string]interface{}, path string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, present := obj[name]; !present {
+				v.fail(joinPointer(path, name), "required property is missing")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := joinPointer(path, key)
+		if propSchema, ok := properties[key]; ok {
+			v.validate(propSchema, obj[key], childPath)
+			continue
+		}
+		if additional, ok := schema["additionalProperties"]; ok {
+			if allowed, isBool := additional.(bool); isBool && !allowed {
+				v.fail(childPath, "additional property is not allowed")
+				continue
+			}
+			v.validate(additional, obj[key], childPath)
+		}
+	}
+}
+
+func (v *schemaValidator) validateArray(schema map[string]interface{}, arr []interface{}, path string) {
+	if n, ok := schemaInt(schema, "minItems"); ok && len(arr) < n {
+		v.fail(path, "array must contain at least %d items, has %d", n, len(arr))
+	}
+	if n, ok := schemaInt(schema, "maxItems"); ok && len(arr) > n {
+		v.fail(path, "array must contain at most %d items, has %d", n, len(arr))
+	}
+	if items, ok := schema["items"]; ok {
+		for i, item := range arr {
+			v.validate(items, item, joinPointer(path, fmt.Sprint(i)))
+		}
+	}
+}
+
+func (v *schemaValidator) validateString(schema map[string]interface{}, str string, path string) {
+	length := utf8.RuneCountInString(str)
+	if n, ok := schemaInt(schema, "minLength"); ok && length < n {
+		v.fail(path, "string must be at least %d characters, has %d", n, length)
+	}
+	if n, ok := schemaInt(schema, "maxLength"); ok && length > n {
+		v.fail(path, "string must be at most %d characters, has %d", n, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "schema pattern %q is invalid: %v", pattern, err)
+		} else if !re.MatchString(str) {
+			v.fail(path, "string does not match pattern %q", pattern)
+		}
+	}
+}
+
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, num json.Number, path string) {
+	f, err := num.Float64()
+	if err != nil {
+		v.fail(path, "invalid number %s", num)
+		return
+	}
+	if min, ok := schemaFloat(schema, "minimum"); ok && f < min {
+		v.fail(path, "value %s is less than minimum %v", num, min)
+	}
+	if max, ok := schemaFloat(schema, "maximum"); ok && f > max {
+		v.fail(path, "value %s is greater than maximum %v", num, max)
+	}
+	if min, ok := schemaFloat(schema, "exclusiveMinimum"); ok && f <= min {
+		v.fail(path, "value %s must be greater than %v", num, min)
+	}
+	if max, ok := schemaFloat(schema, "exclusiveMaximum"); ok && f >= max {
+		v.fail(path, "value %s must be less than %v", num, max)
+	}
+}
+
+func matchesType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, candidate := range t {
+			if name, ok := candidate.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
+func matchesSingleType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := num.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	default:
+		return jsonTypeOf(value) == schemaType
+	}
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func describeType(schemaType interface{}) string {
+	if list, ok := schemaType.([]interface{}); ok {
+		names := make([]string, len(list))
+		for i, n := range list {
+			names[i] = fmt.Sprint(n)
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(schemaType)
+}
+
+func schemaFloat(schema map[string]interface{}, key string) (float64, bool) {
+	switch n := schema[key].(type) {
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func schemaInt(schema map[string]interface{}, key string) (int, bool) {
+	f, ok := schemaFloat(schema, key)
+	return int(f), ok
+}
+
+// jsonEqual compares two decoded JSON values, treating numbers by value.
+func jsonEqual(a, b interface{}) bool {
+	return compactJSON(a) == compactJSON(b)
+}
+
+func compactJSON(value interface{}) string {
+	if num, ok := value.(json.Number); ok {
+		if f, err := num.Float64(); err == nil {
+			value = f
+		}
+	}
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(bytes, &normalized); err == nil {
+		bytes, _ = json.Marshal(normalized)
+	}
+	return string(bytes)
+}
+
+func joinPointer(path, token string) string {
+	token = strings.ReplaceAll(token, "~", "~0")
+	token = strings.ReplaceAll(token, "/", "~1")
+	return path + "/" + token
+}
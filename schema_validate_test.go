@@ -0,0 +1,102 @@
+package rck_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+)
+
+func TestValidateJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		data   string
+		want   []string // Violations as "path: message prefix"; nil when valid
+	}{
+		{"type ok", `{"type": "string"}`, `"hi"`, nil},
+		{"type mismatch", `{"type": "string"}`, `3`, []string{"/: expected string, got number"}},
+		{"type list", `{"type": ["string", "null"]}`, `null`, nil},
+		{"integer", `{"type": "integer"}`, `2.5`, []string{"/: expected integer"}},
+		{"integer ok", `{"type": "integer"}`, `2.0`, nil},
+
+		{"required ok", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, nil},
+		{"required missing", `{"type": "object", "required": ["a", "b"]}`, `{"b": 1}`, []string{"/a: required property is missing"}},
+
+		{"enum ok", `{"enum": ["red", 1]}`, `1.0`, nil},
+		{"enum miss", `{"enum": ["red", "blue"]}`, `"green"`, []string{`/: value "green" is not one of the allowed values`}},
+		{"const", `{"const": {"a": 1}}`, `{"a": 2}`, []string{"/: value must be"}},
+
+		{"items", `{"type": "array", "items": {"type": "number"}}`, `[1, "x", 3, true]`, []string{"/1: expected number", "/3: expected number"}},
+		{"minItems", `{"type": "array", "minItems": 2}`, `[1]`, []string{"/: array must contain at least 2 items"}},
+		{"maxItems", `{"type": "array", "maxItems": 1}`, `[1, 2]`, []string{"/: array must contain at most 1 items"}},
+
+		{"minimum", `{"minimum": 1}`, `0`, []string{"/: value 0 is less than minimum 1"}},
+		{"minimum boundary", `{"minimum": 1, "maximum": 1}`, `1`, nil},
+		{"maximum", `{"maximum": 10}`, `10.5`, []string{"/: value 10.5 is greater than maximum 10"}},
+		{"exclusiveMinimum", `{"exclusiveMinimum": 1}`, `1`, []string{"/: value 1 must be greater than 1"}},
+		{"exclusiveMaximum", `{"exclusiveMaximum": 1}`, `1`, []string{"/: value 1 must be less than 1"}},
+
+		{"minLength", `{"minLength": 3}`, `"ab"`, []string{"/: string must be at least 3 characters"}},
+		{"maxLength counts runes", `{"maxLength": 2}`, `"éé"`, nil},
+		{"maxLength", `{"maxLength": 2}`, `"abc"`, []string{"/: string must be at most 2 characters"}},
+		{"pattern ok", `{"pattern": "^[0-9]+$"}`, `"123"`, nil},
+		{"pattern miss", `{"pattern": "^[0-9]+$"}`, `"12a"`, []string{`/: string does not match pattern`}},
+
+		{"additionalProperties false", `{"properties": {"a": {}}, "additionalProperties": false}`, `{"a": 1, "b": 2}`, []string{"/b: additional property is not allowed"}},
+		{"additionalProperties schema", `{"additionalProperties": {"type": "string"}}`, `{"b": 2}`, []string{"/b: expected string"}},
+
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "number"}]}`, `true`, []string{"/: value does not match any of the anyOf schemas"}},
+		{"oneOf", `{"oneOf": [{"type": "number"}, {"type": "integer"}]}`, `1`, []string{"/: value must match exactly one oneOf schema, matched 2"}},
+		{"allOf", `{"allOf": [{"type": "number"}, {"minimum": 5}]}`, `3`, []string{"/: value 3 is less than minimum 5"}},
+
+		{
+			"nested paths",
+			`{"type": "object", "properties": {"customer": {"type": "object", "required": ["name"], "properties": {"phones": {"type": "array", "items": {"type": "string", "pattern": "^\\+"}}}}}}`,
+			`{"customer": {"phones": ["+1", "555"]}}`,
+			[]string{"/customer/name: required property is missing", "/customer/phones/1: string does not match pattern"},
+		},
+		{
+			"escaped path tokens",
+			`{"properties": {"a/b": {"type": "string"}, "c~d": {"type": "string"}}}`,
+			`{"a/b": 1, "c~d": 2}`,
+			[]string{"/a~1b: expected string", "/c~0d: expected string"},
+		},
+		{"invalid JSON", `{"type": "object"}`, `{"a":`, []string{"/: output is not valid JSON"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := rck.ValidateJSON(tt.schema, []byte(tt.data))
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("ValidateJSON = %v, want nil", err)
+				}
+				return
+			}
+			var violation *rck.SchemaViolationError
+			if !errors.As(err, &violation) {
+				t.Fatalf("ValidateJSON = %v, want a SchemaViolationError", err)
+			}
+			if len(violation.Violations) != len(tt.want) {
+				t.Fatalf("violations = %v, want %q", violation.Violations, tt.want)
+			}
+			for i, v := range violation.Violations {
+				if !strings.HasPrefix(v.String(), tt.want[i]) {
+					t.Errorf("violation %d = %q, want prefix %q", i, v.String(), tt.want[i])
+				}
+			}
+			if string(violation.Output) != tt.data {
+				t.Errorf("Output = %s, want %s", violation.Output, tt.data)
+			}
+		})
+	}
+}
+
+func TestValidateJSONRejectsNonSchema(t *testing.T) {
+	err := rck.ValidateJSON("not a schema", []byte(`{}`))
+	var validation *rck.ValidationError
+	if !errors.As(err, &validation) {
+		t.Errorf("ValidateJSON = %v, want a ValidationError", err)
+	}
+}
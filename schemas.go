@@ -1,9 +1,6 @@
 package rck
 
-import (
-	"encoding/json"
-)
-
+// predefinedSchemas seed DefaultSchemas.
 var predefinedSchemas = map[string]string{
 	"basic_analysis": `{
     "type": "object",
@@ -51,38 +48,25 @@ var predefinedSchemas = map[string]string{
   }`,
 }
 
-// GetPredefinedSchema returns the JSON string for a predefined schema.
+// GetPredefinedSchema returns the JSON string for a schema of DefaultSchemas,
+// with references inlined. The name can be versioned as "name@version".
+// Predefined schemas are returned exactly as defined.
 func GetPredefinedSchema(schemaName string) (string, bool) {
-	schema, ok := predefinedSchemas[schemaName]
-	return schema, ok
+	schema, err := DefaultSchemas.text(schemaName)
+	return schema, err == nil
 }
 
 // GetPredefinedSchemaAsMap returns the schema as a map.
 func GetPredefinedSchemaAsMap(schemaName string) (map[string]interface{}, error) {
-	schemaStr, ok := GetPredefinedSchema(schemaName)
-	if !ok {
-		return nil, NewValidationError("schemaName", "unknown schema name")
-	}
-
-	var schemaMap map[string]interface{}
-	err := json.Unmarshal([]byte(schemaStr), &schemaMap)
-	if err != nil {
-		return nil, err
-	}
-	return schemaMap, nil
+	return DefaultSchemas.SchemaMap(schemaName)
 }
 
 // GetAvailableSchemas returns a list of all available schema names.
 func GetAvailableSchemas() []string {
-	keys := make([]string, 0, len(predefinedSchemas))
-	for k := range predefinedSchemas {
-		keys = append(keys, k)
-	}
-	return keys
+	return DefaultSchemas.Names()
 }
 
-// HasSchema checks if a predefined schema exists.
+// HasSchema checks if a schema is registered in DefaultSchemas.
 func HasSchema(schemaName string) bool {
-	_, ok := predefinedSchemas[schemaName]
-	return ok
+	return DefaultSchemas.Has(schemaName)
 }
@@ -0,0 +1,15 @@
+package rck
+
+import "testing"
+
+func TestGetPredefinedSchemaKeepsOriginalText(t *testing.T) {
+	for name, text := range predefinedSchemas {
+		schema, ok := GetPredefinedSchema(name)
+		if !ok || schema != text {
+			t.Errorf("GetPredefinedSchema(%q) = %q, %v, want the original text", name, schema, ok)
+		}
+	}
+	if _, ok := GetPredefinedSchema("no_such_schema"); ok {
+		t.Error("GetPredefinedSchema found an unknown schema")
+	}
+}
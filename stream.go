@@ -0,0 +1,272 @@
+package rck
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// streamEvent is a single frame of a streamed response. Servers send either
+// incremental deltas, a final aggregated output, or an error.
+type streamEvent struct {
+	Delta   *string         `json:"delta,omitempty"`
+	Output  json.RawMessage `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Details string          `json:"details,omitempty"`
+	Done    bool            `json:"done,omitempty"`
+}
+
+// TextStream delivers the incremental output of GenerateTextStream.
+//
+//	stream, err := client.Compute.GenerateTextStream(ctx, params)
+//	if err != nil { ... }
+//	defer stream.Close()
+//	for stream.Next() {
+//		fmt.Print(stream.Chunk())
+//	}
+//	if err := stream.Err(); err != nil { ... }
+//	full := stream.Text()
+//
+// Both server-sent events (text/event-stream) and concatenated JSON objects are
+// understood. Canceling the context passed to GenerateTextStream aborts the stream.
+type TextStream struct {
+	ctx     context.Context
+	body    io.ReadCloser
+	release func()
+	sse     *bufio.Reader
+	decoder *json.Decoder
+
+	chunk    string
+	text     strings.Builder
+	final    *string
+	err      error
+	finished bool
+}
+
+func newTextStream(ctx context.Context, resp *http.Response, release func()) *TextStream {
+	s := &TextStream{ctx: ctx, body: resp.Body, release: release}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		s.sse = bufio.NewReader(resp.Body)
+	} else {
+		s.decoder = json.NewDecoder(resp.Body)
+	}
+	return s
+}
+
+// Next advances to the next chunk. It returns false when the stream ends or fails; check Err afterwards.
+func (s *TextStream) Next() bool {
+	for !s.finished {
+		event, err := s.readEvent()
+		if err != nil {
+			if err != io.EOF {
+				s.fail(err)
+			}
+			s.finish()
+			return false
+		}
+		if s.handle(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Chunk returns the text delivered by the last successful call to Next.
+func (s *TextStream) Chunk() string {
+	return s.chunk
+}
+
+// Text returns the aggregated text. After the stream ends it is the final result,
+// which is the server's aggregated output when it sends one.
+func (s *TextStream) Text() string {
+	if s.final != nil {
+		return *s.final
+	}
+	return s.text.String()
+}
+
+// Err returns the error that ended the stream, if any.
+func (s *TextStream) Err() error {
+	return s.err
+}
+
+// Close stops the stream and releases its connection. It is safe to call more than once.
+func (s *TextStream) Close() error {
+	s.finish()
+	return nil
+}
+
+// Collect reads the rest of the stream and returns the aggregated text.
+func (s *TextStream) Collect() (string, error) {
+	defer s.Close()
+	for s.Next() {
+	}
+	return s.Text(), s.Err()
+}
+
+func (s *TextStream) finish() {
+	if s.finished {
+		return
+	}
+	s.finished = true
+	s.body.Close()
+	s.release()
+}
+
+func (s *TextStream) fail(err error) {
+	if cause := contextCause(s.ctx); cause != "" {
+		s.err = newNetworkError("stream aborted", cause, s.ctx.Err())
+		return
+	}
+	if _, ok := err.(*APIError); ok {
+		s.err = err
+		return
+	}
+	s.err = newNetworkError("failed to read stream", classifyBodyError(s.ctx, err), err)
+}
+
+// handle applies an event and reports whether it produced a chunk.
+func (s *TextStream) handle(event streamEvent) bool {
+	if event.Error != "" {
+		s.fail(&APIError{
+			StatusCode:   http.StatusOK,
+			ResponseData: &UnifiedAPIResponse{Error: event.Error, Details: event.Details},
+			Kind:         KindServer,
+		})
+		s.finish()
+		return false
+	}
+
+	emitted := false
+	if event.Delta != nil && *event.Delta != "" {
+		s.chunk = *event.Delta
+		s.text.WriteString(s.chunk)
+		emitted = true
+	}
+	if len(event.Output) > 0 && string(event.Output) != "null" {
+		var output string
+		if err := json.Unmarshal(event.Output, &output); err != nil {
+			output = string(event.Output)
+		}
+		s.final = &output
+		// A server that does not stream sends only the final output; surface it as one chunk.
+		if s.text.Len() == 0 && !emitted && output != "" {
+			s.chunk = output
+			s.text.WriteString(output)
+			emitted = true
+		}
+	}
+	if event.Done {
+		s.finish()
+	}
+	return emitted
+}
+
+func (s *TextStream) readEvent() (streamEvent, error) {
+	if s.decoder != nil {
+		var event streamEvent
+		err := s.decoder.Decode(&event)
+		return event, err
+	}
+	return s.readSSE()
+}
+
+// readSSE reads one server-sent event. Frames whose data is a JSON string or not JSON at all are text deltas.
+func (s *TextStream) readSSE() (streamEvent, error) {
+	var data bytes.Buffer
+	eventName := ""
+	for {
+		line, err := s.sse.ReadString('\n')
+		if line == "" && err != nil {
+			if data.Len() == 0 && eventName == "" {
+				return streamEvent{}, err
+			}
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			if data.Len() == 0 && eventName == "" {
+				continue
+			}
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, ":"):
+			// Comment or keep-alive.
+		case strings.HasPrefix(line, "event:"):
+			eventName = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+		if err != nil {
+			break
+		}
+	}
+
+	payload := data.String()
+	switch {
+	case payload == "[DONE]" || (eventName == "done" && payload == ""):
+		return streamEvent{Done: true}, nil
+	case eventName == "error":
+		var event streamEvent
+		if json.Unmarshal([]byte(payload), &event) != nil || event.Error == "" {
+			event = streamEvent{Error: payload}
+		}
+		return event, nil
+	}
+
+	// A JSON object is an event; one with none of its fields carries no text and is dropped by handle.
+	// A JSON string is the delta itself. Anything else is plain text.
+	var event streamEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		var text string
+		if json.Unmarshal([]byte(payload), &text) != nil {
+			text = payload
+		}
+		event = streamEvent{Delta: &text}
+	}
+	if eventName == "done" {
+		event.Done = true
+	}
+	return event, nil
+}
+
+// GenerateTextStream is like GenerateText but delivers the text incrementally as the server produces it.
+// The caller must Close the returned stream. Streams are not retried once opened.
+func (k *Kernel) GenerateTextStream(ctx context.Context, params GenerateTextParams, config ...ComputeConfig) (*TextStream, error) {
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return nil, err
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	program := APIProgram{
+		Input: APIInput{
+			Input:    params.Input,
+			Resource: params.Resource,
+		},
+		Pipeline: APIPipeline{
+			FunctionLogic: params.FunctionLogic,
+			CustomLogic:   params.CustomLogic,
+		},
+	}
+	apiConfig := &APIConfig{Engine: EnginePure, Stream: true}
+	if len(config) > 0 {
+		apiConfig.ComputeConfig = config[0]
+	}
+
+	resp, release, err := k.client.PostStream(ctx, unifiedEndpoint, &UnifiedAPIRequest{Program: program, Config: apiConfig})
+	if err != nil {
+		return nil, err
+	}
+	return newTextStream(ctx, resp, release), nil
+}
@@ -0,0 +1,193 @@
+package rck_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+func newStreamServer(t *testing.T, reply rcktest.Reply) *rck.Client {
+	t.Helper()
+	server := rcktest.NewServer()
+	t.Cleanup(server.Close)
+	server.Handle(rck.EnginePure, func(req *rck.UnifiedAPIRequest) rcktest.Reply {
+		return reply
+	})
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func readChunks(t *testing.T, stream *rck.TextStream) []string {
+	t.Helper()
+	defer stream.Close()
+	var chunks []string
+	for stream.Next() {
+		chunks = append(chunks, stream.Chunk())
+	}
+	return chunks
+}
+
+func TestTextStreamDeliversChunks(t *testing.T) {
+	client := newStreamServer(t, rcktest.Reply{Stream: []string{"Hel", "lo", "!"}})
+	stream, err := client.Compute.GenerateTextStream(context.Background(), textParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := readChunks(t, stream)
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 3 || chunks[0] != "Hel" || chunks[1] != "lo" || chunks[2] != "!" {
+		t.Errorf("chunks = %q", chunks)
+	}
+	if got := stream.Text(); got != "Hello!" {
+		t.Errorf("text = %q, want %q", got, "Hello!")
+	}
+}
+
+func TestTextStreamParsesFrames(t *testing.T) {
+	sse := http.Header{"Content-Type": {"text/event-stream"}}
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+	tests := []struct {
+		name   string
+		header http.Header
+		body   string
+		chunks []string
+		text   string
+	}{
+		{
+			name:   "comments and CRLF",
+			header: sse,
+			body:   ": keep-alive\r\n\r\ndata: {\"delta\":\"a\"}\r\n\r\ndata: {\"delta\":\"b\"}\r\n\r\ndata: [DONE]\r\n\r\n",
+			chunks: []string{"a", "b"},
+			text:   "ab",
+		},
+		{
+			name:   "plain text and multi-line data",
+			header: sse,
+			body:   "data: plain\n\ndata: line1\ndata: line2\n\nevent: done\ndata:\n\ndata: ignored\n\n",
+			chunks: []string{"plain", "line1\nline2"},
+			text:   "plainline1\nline2",
+		},
+		{
+			name:   "final output replaces text",
+			header: sse,
+			body:   "data: {\"delta\":\"draft\"}\n\ndata: {\"output\":\"final\",\"done\":true}\n\n",
+			chunks: []string{"draft"},
+			text:   "final",
+		},
+		{
+			name:   "JSON string data",
+			header: sse,
+			body:   "data: \"hi\"\n\ndata: \"a\\nb\"\n\n",
+			chunks: []string{"hi", "a\nb"},
+			text:   "hia\nb",
+		},
+		{
+			name:   "objects without text are ignored",
+			header: sse,
+			body:   "data: {}\n\ndata: {\"delta\":\"a\"}\n\ndata: {\"id\":1}\n\ndata: null\n\n",
+			chunks: []string{"a"},
+			text:   "a",
+		},
+		{
+			name:   "unterminated last frame",
+			header: sse,
+			body:   "data: {\"delta\":\"a\"}\n\ndata: {\"delta\":\"b\"}",
+			chunks: []string{"a", "b"},
+			text:   "ab",
+		},
+		{
+			name:   "concatenated JSON",
+			header: jsonHeader,
+			body:   `{"delta":"a"}{"delta":"b"}{"output":"ab","done":true}`,
+			chunks: []string{"a", "b"},
+			text:   "ab",
+		},
+		{
+			name:   "non-streaming server",
+			header: jsonHeader,
+			body:   `{"output":"whole"}`,
+			chunks: []string{"whole"},
+			text:   "whole",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newStreamServer(t, rcktest.Reply{Header: tt.header, Body: []byte(tt.body)})
+			stream, err := client.Compute.GenerateTextStream(context.Background(), textParams)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunks := readChunks(t, stream)
+			if err := stream.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if len(chunks) != len(tt.chunks) {
+				t.Fatalf("chunks = %q, want %q", chunks, tt.chunks)
+			}
+			for i := range chunks {
+				if chunks[i] != tt.chunks[i] {
+					t.Errorf("chunks = %q, want %q", chunks, tt.chunks)
+					break
+				}
+			}
+			if got := stream.Text(); got != tt.text {
+				t.Errorf("text = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestTextStreamErrorEvent(t *testing.T) {
+	client := newStreamServer(t, rcktest.Reply{
+		Header: http.Header{"Content-Type": {"text/event-stream"}},
+		Body:   []byte("data: {\"delta\":\"a\"}\n\nevent: error\ndata: {\"error\":\"overloaded\",\"details\":\"try later\"}\n\n"),
+	})
+	stream, err := client.Compute.GenerateTextStream(context.Background(), textParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := stream.Collect()
+	var apiErr *rck.APIError
+	if !errors.As(err, &apiErr) || apiErr.Kind != rck.KindServer {
+		t.Fatalf("error = %#v, want a server APIError", err)
+	}
+	if apiErr.ResponseData == nil || apiErr.ResponseData.Error != "overloaded" {
+		t.Errorf("response data = %+v", apiErr.ResponseData)
+	}
+	if text != "a" {
+		t.Errorf("text = %q, want the chunks received before the error", text)
+	}
+}
+
+func TestTextStreamCanceled(t *testing.T) {
+	client := newStreamServer(t, rcktest.Reply{Stream: []string{"a", "b", "c"}, StreamDelay: 200 * time.Millisecond})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.Compute.GenerateTextStream(ctx, textParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	if !stream.Next() || stream.Chunk() != "a" {
+		t.Fatalf("first chunk = %q, err = %v", stream.Chunk(), stream.Err())
+	}
+	cancel()
+	if stream.Next() {
+		t.Fatalf("got chunk %q after cancellation", stream.Chunk())
+	}
+	var netErr *rck.NetworkError
+	if !errors.As(stream.Err(), &netErr) {
+		t.Errorf("error = %#v, want a NetworkError", stream.Err())
+	}
+}
@@ -0,0 +1,52 @@
+package rck
+
+import (
+	"context"
+	"fmt"
+)
+
+// Transform performs a structured transformation whose output schema is derived
+// from T (see SchemaFor) and decodes the result into a value of type T.
+//
+//	type Customer struct {
+//		Name  string `json:"name" rck:"description=Customer name"`
+//		Phone string `json:"phone"`
+//	}
+//	customer, err := rck.Transform[Customer](ctx, client.Compute, rck.TransformParams{
+//		Input:         "客户张三，电话13800138000",
+//		FunctionLogic: "提取客户姓名和电话",
+//	})
+func Transform[T any](ctx context.Context, kernel *Kernel, params TransformParams, config ...ComputeConfig) (T, error) {
+	var result T
+	ctx, err := applyPrompts(ctx, &params, params.Templates)
+	if err != nil {
+		return result, err
+	}
+	if err := params.Validate(); err != nil {
+		return result, err
+	}
+
+	schema, err := SchemaFor[T]()
+	if err != nil {
+		return result, NewValidationError("OutputDataClass", err.Error())
+	}
+	if schema["type"] != "object" {
+		return result, NewValidationError("OutputDataClass", fmt.Sprintf("type %T must map to a JSON object", result))
+	}
+
+	response, err := kernel.StructuredTransform(ctx, StructuredTransformParams{
+		Input:           params.Input,
+		FunctionLogic:   params.FunctionLogic,
+		OutputDataClass: schema,
+		CustomLogic:     params.CustomLogic,
+		Resource:        params.Resource,
+	}, config...)
+	if err != nil {
+		return result, err
+	}
+
+	if err := response.Decode(&result); err != nil {
+		return result, fmt.Errorf("failed to decode output into %T: %w", result, err)
+	}
+	return result, nil
+}
@@ -1,6 +1,9 @@
 package rck
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"net/http"
+)
 
 // Engine defines the type for the compute engine.
 type Engine string
@@ -38,20 +41,40 @@ const (
 type ClientOptions struct {
 	Timeout int // Request timeout in milliseconds
 	BaseURL string
+	Retry   *RetryPolicy // Retry policy for failed requests; nil disables retries
+
+	// HTTPClient is used for requests instead of a client built by the SDK.
+	// Timeout is only applied to it when the client has no timeout of its own.
+	HTTPClient *http.Client
+	// Transport is the http.RoundTripper used for requests; nil keeps the
+	// HTTPClient's transport, or http.DefaultTransport.
+	Transport http.RoundTripper
+	// Middleware wraps every call, first element outermost. See Middleware.
+	Middleware []Middleware
+	// RateLimit enables a client-side limiter shared by all Kernel and Generator calls.
+	RateLimit *RateLimitOptions
+	// Cache serves repeated Kernel calls from a response cache. See CacheOptions and WithoutCache.
+	Cache *CacheOptions
+	// Deduplicate makes concurrent identical requests share a single HTTP call.
+	// A caller whose context ends stops waiting; the call is canceled once no caller waits.
+	Deduplicate bool
+	// Schemas is the registry Analyze and Translate look schemas up in; nil uses DefaultSchemas.
+	Schemas *SchemaRegistry
 }
 
 // ComputeConfig holds execution configuration for a compute request.
 // It corresponds to the 'config' object in the API, excluding the 'engine'.
 type ComputeConfig struct {
-	Speed       Speed    `json:"speed,omitempty"`
-	Scale       Scale    `json:"scale,omitempty"`
-	Temperature *float64 `json:"temperature,omitempty"`
+	Speed       Speed    `json:"speed,omitempty" yaml:"speed,omitempty"`
+	Scale       Scale    `json:"scale,omitempty" yaml:"scale,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty" yaml:"temperature,omitempty"`
 }
 
 // APIConfig is the complete configuration sent to the API.
 type APIConfig struct {
 	ComputeConfig
 	Engine Engine `json:"engine"`
+	Stream bool   `json:"stream,omitempty"` // Ask the server to stream the output incrementally
 }
 
 // APIInput represents the input data for a program.
@@ -96,4 +119,5 @@ type UnifiedAPIResponse struct {
 	Output  json.RawMessage `json:"output"`
 	Error   string          `json:"error,omitempty"`
 	Details string          `json:"details,omitempty"`
+	Engine  Engine          `json:"engine,omitempty"` // Engine that ran the program, when the server reports it
 }
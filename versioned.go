@@ -0,0 +1,111 @@
+package rck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// versioned holds named items by version, as used by the prompt, schema and function
+// registries. Each name's versions are sorted in ascending order. The caller synchronizes access.
+type versioned[T any] struct {
+	kind    string // Item kind used in errors, e.g. "template"
+	version func(T) string
+	items   map[string][]T
+}
+
+func newVersioned[T any](kind string, version func(T) string) versioned[T] {
+	return versioned[T]{kind: kind, version: version, items: map[string][]T{}}
+}
+
+// add stores item under name, keeping the versions sorted. It reports false,
+// storing nothing, when the item's version is already present.
+func (v versioned[T]) add(name string, item T) bool {
+	for _, existing := range v.items[name] {
+		if v.version(existing) == v.version(item) {
+			return false
+		}
+	}
+	versions := append(v.items[name], item)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return compareVersions(v.version(versions[i]), v.version(versions[j])) < 0
+	})
+	v.items[name] = versions
+	return true
+}
+
+// get returns the item for "name@version", or the latest version for a bare name.
+func (v versioned[T]) get(ref string) (T, error) {
+	var zero T
+	name, version, pinned := strings.Cut(ref, "@")
+	versions := v.items[name]
+	if len(versions) == 0 {
+		return zero, fmt.Errorf("unknown %s %q", v.kind, name)
+	}
+	if !pinned {
+		return versions[len(versions)-1], nil
+	}
+	for _, item := range versions {
+		if v.version(item) == version {
+			return item, nil
+		}
+	}
+	return zero, fmt.Errorf("unknown version %q of %s %q", version, v.kind, name)
+}
+
+// names returns the stored names in sorted order.
+func (v versioned[T]) names() []string {
+	names := make([]string, 0, len(v.items))
+	for name := range v.items {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versions returns the versions stored under name, oldest first.
+func (v versioned[T]) versions(name string) []string {
+	versions := make([]string, len(v.items[name]))
+	for i, item := range v.items[name] {
+		versions[i] = v.version(item)
+	}
+	return versions
+}
+
+// compareVersions orders versions such as "v2" < "v10" or "1.9" < "1.10" by comparing
+// runs of digits numerically and other runs lexically.
+func compareVersions(a, b string) int {
+	a, b = strings.TrimPrefix(a, "v"), strings.TrimPrefix(b, "v")
+	for a != "" && b != "" {
+		chunkA, restA := versionChunk(a)
+		chunkB, restB := versionChunk(b)
+		if c := compareChunks(chunkA, chunkB); c != 0 {
+			return c
+		}
+		a, b = restA, restB
+	}
+	return strings.Compare(a, b)
+}
+
+func versionChunk(s string) (string, string) {
+	digits := s[0] >= '0' && s[0] <= '9'
+	i := 1
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func compareChunks(a, b string) int {
+	aDigits, bDigits := a[0] >= '0' && a[0] <= '9', b[0] >= '0' && b[0] <= '9'
+	if aDigits && bDigits {
+		a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+		if len(a) != len(b) {
+			if len(a) < len(b) {
+				return -1
+			}
+			return 1
+		}
+	}
+	return strings.Compare(a, b)
+}
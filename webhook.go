@@ -0,0 +1,244 @@
+package rck
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Headers carrying the signature of a webhook delivery.
+const (
+	WebhookSignatureHeader = "X-RCK-Signature" // "sha256=" followed by the hex HMAC of "<timestamp>.<body>"
+	WebhookTimestampHeader = "X-RCK-Timestamp" // Unix time in seconds at which the delivery was signed
+)
+
+const (
+	defaultWebhookTolerance = 5 * time.Minute
+	maxWebhookBody          = 10 << 20
+)
+
+// SignWebhook returns the signature header value for a delivery body signed at timestamp.
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookEvent is a job completion event delivered to a WebhookHandler.
+type WebhookEvent struct {
+	JobID     string              `json:"job_id"`
+	RequestID string              `json:"request_id,omitempty"`
+	Status    JobStatus           `json:"status"`
+	Response  *UnifiedAPIResponse `json:"response,omitempty"`
+	Error     string              `json:"error,omitempty"`
+	Details   string              `json:"details,omitempty"`
+	Timestamp time.Time           `json:"-"` // Signing time of the delivery
+}
+
+// Result returns the job's result, or a *JobError when the job failed or was canceled.
+func (e *WebhookEvent) Result() (*JobResult, error) {
+	state := jobState{ID: e.JobID, Status: e.Status, Response: e.Response, Error: e.Error, Details: e.Details}
+	return state.result()
+}
+
+// key returns the ID the event is dispatched by.
+func (e *WebhookEvent) key() string {
+	if e.JobID != "" {
+		return e.JobID
+	}
+	return e.RequestID
+}
+
+// WebhookHandler is an http.Handler that receives job completion callbacks
+// (see JobOptions.CallbackURL), verifies their HMAC signature and dispatches
+// them by job ID, or by request ID for deliveries without one.
+//
+//	hooks, err := rck.NewWebhookHandler(secret)
+//	http.Handle("/rck/callback", hooks)
+//	job, _ := client.Jobs.Submit(ctx, request, rck.JobOptions{CallbackURL: "https://example.com/rck/callback"})
+//	event := <-hooks.Await(job.ID)
+//
+// Deliveries signed more than Tolerance away from the local clock, and deliveries
+// whose signature was already accepted, are rejected. Events that arrive before a
+// callback or channel is registered for their ID are held for Tolerance.
+type WebhookHandler struct {
+	Tolerance time.Duration // Maximum clock difference accepted; defaults to 5 minutes
+
+	secret string
+
+	mu        sync.Mutex
+	callbacks map[string]func(WebhookEvent)
+	fallback  func(WebhookEvent)
+	pending   map[string]WebhookEvent
+	seen      map[string]time.Time // Accepted signatures, by signing time
+}
+
+// NewWebhookHandler creates a handler verifying deliveries with the shared secret.
+// The secret is required: anyone could sign deliveries with an empty one.
+func NewWebhookHandler(secret string) (*WebhookHandler, error) {
+	if secret == "" {
+		return nil, NewValidationError("Secret", "is required")
+	}
+	return &WebhookHandler{
+		secret:    secret,
+		callbacks: map[string]func(WebhookEvent){},
+		pending:   map[string]WebhookEvent{},
+		seen:      map[string]time.Time{},
+	}, nil
+}
+
+// On registers a callback for the event of a job or request ID. The callback runs once,
+// on the goroutine serving the delivery; an event that already arrived is dispatched immediately.
+func (h *WebhookHandler) On(id string, callback func(WebhookEvent)) {
+	h.mu.Lock()
+	event, ok := h.pending[id]
+	if ok {
+		delete(h.pending, id)
+	} else {
+		h.callbacks[id] = callback
+	}
+	h.mu.Unlock()
+
+	if ok {
+		callback(event)
+	}
+}
+
+// Await returns a channel that receives the event of a job or request ID.
+func (h *WebhookHandler) Await(id string) <-chan WebhookEvent {
+	ch := make(chan WebhookEvent, 1)
+	h.On(id, func(event WebhookEvent) { ch <- event })
+	return ch
+}
+
+// Forget removes the callback or held event of an ID.
+func (h *WebhookHandler) Forget(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.callbacks, id)
+	delete(h.pending, id)
+}
+
+// OnUnmatched sets a callback for events without a registered ID. They are then no longer held.
+func (h *WebhookHandler) OnUnmatched(callback func(WebhookEvent)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.fallback = callback
+}
+
+// ServeHTTP verifies and dispatches a delivery. It answers 204 on success, 401 for a bad
+// signature or timestamp, 409 for a replayed delivery and 400 for a malformed payload.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBody))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	timestamp, err := h.verify(r.Header, body, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if event.key() == "" || !event.Status.Done() {
+		http.Error(w, "payload must carry a job_id or request_id and a final status", http.StatusBadRequest)
+		return
+	}
+	if event.Response == nil {
+		event.Response = &UnifiedAPIResponse{Error: event.Error, Details: event.Details}
+	}
+	event.Timestamp = timestamp
+
+	signature := r.Header.Get(WebhookSignatureHeader)
+	h.mu.Lock()
+	h.prune(now)
+	if _, ok := h.seen[signature]; ok {
+		h.mu.Unlock()
+		http.Error(w, "delivery already received", http.StatusConflict)
+		return
+	}
+	h.seen[signature] = timestamp
+	callback, ok := h.callbacks[event.key()]
+	if ok {
+		delete(h.callbacks, event.key())
+	} else if h.fallback != nil {
+		callback = h.fallback
+	} else {
+		h.pending[event.key()] = event
+	}
+	h.mu.Unlock()
+
+	if callback != nil {
+		callback(event)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) tolerance() time.Duration {
+	if h.Tolerance > 0 {
+		return h.Tolerance
+	}
+	return defaultWebhookTolerance
+}
+
+// verify checks the timestamp and signature headers and returns the signing time.
+func (h *WebhookHandler) verify(header http.Header, body []byte, now time.Time) (time.Time, error) {
+	if h.secret == "" {
+		return time.Time{}, errors.New("handler has no secret; use NewWebhookHandler")
+	}
+	seconds, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("missing or invalid %s header", WebhookTimestampHeader)
+	}
+	timestamp := time.Unix(seconds, 0)
+	if skew := now.Sub(timestamp); skew > h.tolerance() || skew < -h.tolerance() {
+		return time.Time{}, errors.New("timestamp outside tolerance")
+	}
+
+	signature := header.Get(WebhookSignatureHeader)
+	if !strings.HasPrefix(signature, "sha256=") {
+		return time.Time{}, fmt.Errorf("missing or invalid %s header", WebhookSignatureHeader)
+	}
+	expected := SignWebhook(h.secret, timestamp, body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return time.Time{}, errors.New("signature mismatch")
+	}
+	return timestamp, nil
+}
+
+// prune forgets signatures and held events older than the tolerance. The caller must hold h.mu.
+func (h *WebhookHandler) prune(now time.Time) {
+	cutoff := now.Add(-h.tolerance())
+	for signature, timestamp := range h.seen {
+		if timestamp.Before(cutoff) {
+			delete(h.seen, signature)
+		}
+	}
+	for id, event := range h.pending {
+		if event.Timestamp.Before(cutoff) {
+			delete(h.pending, id)
+		}
+	}
+}
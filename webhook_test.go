@@ -0,0 +1,180 @@
+package rck_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	rck "github.com/Askr-Omorsablin/rck-go-sdk"
+	"github.com/Askr-Omorsablin/rck-go-sdk/rcktest"
+)
+
+const testWebhookSecret = "whsec-test"
+
+func deliverWebhook(h http.Handler, secret string, signedAt time.Time, body []byte) int {
+	req := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader(body))
+	req.Header.Set(rck.WebhookTimestampHeader, strconv.FormatInt(signedAt.Unix(), 10))
+	req.Header.Set(rck.WebhookSignatureHeader, rck.SignWebhook(secret, signedAt, body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestNewWebhookHandlerRequiresSecret(t *testing.T) {
+	_, err := rck.NewWebhookHandler("")
+	var validation *rck.ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("NewWebhookHandler(\"\") error = %v, want *ValidationError", err)
+	}
+}
+
+func TestWebhookHandlerVerifiesSignature(t *testing.T) {
+	body := []byte(`{"job_id":"job-1","status":"succeeded","response":{"output":"done"}}`)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		secret   string
+		signedAt time.Time
+		body     []byte
+		want     int
+	}{
+		{"valid", testWebhookSecret, now, body, http.StatusNoContent},
+		{"wrong secret", "other", now, body, http.StatusUnauthorized},
+		{"empty secret", "", now, body, http.StatusUnauthorized},
+		{"stale timestamp", testWebhookSecret, now.Add(-10 * time.Minute), body, http.StatusUnauthorized},
+		{"future timestamp", testWebhookSecret, now.Add(10 * time.Minute), body, http.StatusUnauthorized},
+		{"unfinished job", testWebhookSecret, now, []byte(`{"job_id":"job-1","status":"running"}`), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := rck.NewWebhookHandler(testWebhookSecret)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := deliverWebhook(hooks, tt.secret, tt.signedAt, tt.body); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerRejectsTamperedBody(t *testing.T) {
+	hooks, err := rck.NewWebhookHandler(testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	signed := []byte(`{"job_id":"job-1","status":"succeeded"}`)
+	req := httptest.NewRequest(http.MethodPost, "/callback", bytes.NewReader([]byte(`{"job_id":"job-2","status":"succeeded"}`)))
+	req.Header.Set(rck.WebhookTimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(rck.WebhookSignatureHeader, rck.SignWebhook(testWebhookSecret, now, signed))
+	rec := httptest.NewRecorder()
+	hooks.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestWebhookHandlerRejectsReplay(t *testing.T) {
+	hooks, err := rck.NewWebhookHandler(testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := []byte(`{"job_id":"job-1","status":"succeeded","response":{"output":"done"}}`)
+	now := time.Now()
+	if got := deliverWebhook(hooks, testWebhookSecret, now, body); got != http.StatusNoContent {
+		t.Fatalf("first delivery status = %d, want %d", got, http.StatusNoContent)
+	}
+	if got := deliverWebhook(hooks, testWebhookSecret, now, body); got != http.StatusConflict {
+		t.Errorf("replayed delivery status = %d, want %d", got, http.StatusConflict)
+	}
+
+	select {
+	case event := <-hooks.Await("job-1"):
+		if result, _ := event.Result(); result == nil || result.Text() != "done" {
+			t.Errorf("held event result = %+v", result)
+		}
+	default:
+		t.Fatal("event delivered before Await was not held")
+	}
+}
+
+func TestWebhookCallbackFromJob(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+	server.SetWebhookSecret(testWebhookSecret)
+
+	hooks, err := rck.NewWebhookHandler(testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	callbacks := httptest.NewServer(hooks)
+	defer callbacks.Close()
+
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := rck.UnifiedAPIRequest{
+		Program: rck.APIProgram{Input: rck.APIInput{Input: "hello"}, Pipeline: rck.APIPipeline{FunctionLogic: "echo"}},
+		Config:  &rck.APIConfig{Engine: rck.EnginePure},
+	}
+	job, err := client.Jobs.Submit(context.Background(), request, rck.JobOptions{CallbackURL: callbacks.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-hooks.Await(job.ID):
+		if event.Status != rck.JobSucceeded {
+			t.Errorf("event status = %s, want %s", event.Status, rck.JobSucceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+	}
+}
+
+func TestUnsignedCallbackIsRejected(t *testing.T) {
+	server := rcktest.NewServer()
+	defer server.Close()
+
+	hooks, err := rck.NewWebhookHandler(testWebhookSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := make(chan int, 1)
+	callbacks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		hooks.ServeHTTP(rec, r)
+		received <- rec.Code
+		w.WriteHeader(rec.Code)
+	}))
+	defer callbacks.Close()
+
+	client, err := rck.NewClient("test-key", server.ClientOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := rck.UnifiedAPIRequest{
+		Program: rck.APIProgram{Input: rck.APIInput{Input: "hello"}, Pipeline: rck.APIPipeline{FunctionLogic: "echo"}},
+		Config:  &rck.APIConfig{Engine: rck.EnginePure},
+	}
+	if _, err := client.Jobs.Submit(context.Background(), request, rck.JobOptions{CallbackURL: callbacks.URL}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case code := <-received:
+		if code != http.StatusUnauthorized {
+			t.Errorf("unsigned callback status = %d, want %d", code, http.StatusUnauthorized)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no callback received")
+	}
+}